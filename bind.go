@@ -0,0 +1,241 @@
+package httpmux
+
+import (
+	"context"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//BindTag is the struct field tag key that BindVariables reads variable names
+//from.
+const BindTag = "httpmux"
+
+//bindLayoutOption is the tag option that sets the layout time.Time fields are
+//parsed with. It must be the only option since layouts may contain commas.
+const bindLayoutOption = "layout="
+
+//VariableInt returns the Value of the Variable named name parsed as an int.
+//An ErrMissingVariable is returned if there is no such Variable and an
+//*ErrInvalidVariable if its Value is not an int.
+func VariableInt(c context.Context, name string) (int, error) {
+	value, err := requiredVariableValue(c, name)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, newErrInvalidVariable(name, value, err)
+	}
+	return result, nil
+}
+
+//VariableInt64 is the same as VariableInt except that the Value is parsed as
+//an int64.
+func VariableInt64(c context.Context, name string) (int64, error) {
+	value, err := requiredVariableValue(c, name)
+	if err != nil {
+		return 0, err
+	}
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, newErrInvalidVariable(name, value, err)
+	}
+	return result, nil
+}
+
+//VariableUUID is the same as VariableInt except that the Value is parsed as a
+//UUID in the canonical 8-4-4-4-12 hexadecimal form, as matched by
+//ConstraintUUID.
+func VariableUUID(c context.Context, name string) ([16]byte, error) {
+	value, err := requiredVariableValue(c, name)
+	if err != nil {
+		return [16]byte{}, err
+	}
+	result, err := parseUUID(value)
+	if err != nil {
+		return [16]byte{}, newErrInvalidVariable(name, value, err)
+	}
+	return result, nil
+}
+
+func parseUUID(value string) ([16]byte, error) {
+	var result [16]byte
+	if !isUUID(value) {
+		return result, fmt.Errorf("invalid UUID %q", value)
+	}
+	buf := make([]byte, 0, 32)
+	for i := 0; i < len(value); i++ {
+		if value[i] != '-' {
+			buf = append(buf, value[i])
+		}
+	}
+	_, err := hex.Decode(result[:], buf)
+	return result, err
+}
+
+//VariableTime is the same as VariableInt except that the Value is parsed with
+//time.Parse and layout.
+func VariableTime(c context.Context, name, layout string) (time.Time, error) {
+	value, err := requiredVariableValue(c, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	result, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, newErrInvalidVariable(name, value, err)
+	}
+	return result, nil
+}
+
+func requiredVariableValue(c context.Context, name string) (string, error) {
+	v, ok := VariableFromOk(c, name)
+	if !ok {
+		return "", ErrMissingVariable(name)
+	}
+	return v.Value, nil
+}
+
+//BindVariables sets the exported fields of the struct pointed to by dst from
+//the Variables in c. A field is set from the Variable named by its BindTag tag,
+//e.g. `httpmux:"id"`, and is left unchanged if there is no such Variable.
+//Fields tagged `httpmux:"-"` are skipped.
+//
+//Fields may be strings, bools, integers, floats, time.Times, [16]bytes, or
+//implement encoding.TextUnmarshaler. [16]byte fields are parsed as UUIDs, see
+//VariableUUID. time.Time fields are parsed with
+//time.RFC3339 unless the tag has a layout option, e.g.
+//`httpmux:"day,layout=2006-01-02"`.
+//
+//Every field is attempted. If any fail, then an ErrBindVariables is returned
+//with an *ErrInvalidVariable for each of them. An *ErrInvalidBindTarget is
+//returned if dst is not a non-nil pointer to a struct, and an
+//*ErrInvalidBindTag, without setting any field, if a tag has no name or an
+//option other than layout.
+func BindVariables(c context.Context, dst interface{}) error {
+	target := reflect.ValueOf(dst)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return &ErrInvalidBindTarget{Type: reflect.TypeOf(dst)}
+	}
+	target = target.Elem()
+
+	fields := []*bindField{}
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Type().Field(i)
+		tag, ok := field.Tag.Lookup(BindTag)
+		if !ok || tag == "-" || len(field.PkgPath) > 0 {
+			continue
+		}
+		f, err := parseBindTag(field, tag)
+		if err != nil {
+			return err
+		}
+		f.value = target.Field(i)
+		fields = append(fields, f)
+	}
+
+	var errs ErrBindVariables
+	for _, f := range fields {
+		v, ok := VariableFromOk(c, f.name)
+		if !ok {
+			continue
+		}
+		if err := bindValue(f.value, v.Value, f.layout); err != nil {
+			errs = append(errs, newErrInvalidVariable(f.name, v.Value, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//bindField is a field of a BindVariables destination and its parsed tag.
+type bindField struct {
+	value  reflect.Value
+	name   string
+	layout string
+}
+
+//parseBindTag parses the BindTag tag of field. The tag is a variable name
+//optionally followed by a comma and the layout option.
+func parseBindTag(field reflect.StructField, tag string) (*bindField, error) {
+	result := &bindField{name: tag, layout: time.RFC3339}
+	if index := strings.IndexByte(tag, ','); index >= 0 {
+		option := tag[index+1:]
+		if !strings.HasPrefix(option, bindLayoutOption) {
+			return nil, &ErrInvalidBindTag{Field: field.Name, Tag: tag}
+		}
+		result.name, result.layout = tag[:index], option[len(bindLayoutOption):]
+	}
+	if len(result.name) == 0 {
+		return nil, &ErrInvalidBindTag{Field: field.Name, Tag: tag}
+	}
+	return result, nil
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf([16]byte{})
+)
+
+func bindValue(field reflect.Value, value, layout string) error {
+	if field.Type() == timeType {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if field.Type() == uuidType {
+		u, err := parseUUID(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(u))
+		return nil
+	}
+
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+	return nil
+}
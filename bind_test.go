@@ -0,0 +1,171 @@
+package httpmux
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func contextWithVariables(vars ...Variable) context.Context {
+	return newVariablesContext(context.Background(), &match{vars: vars})
+}
+
+func TestVariableInt_parsesOrReturnsErrors(t *testing.T) {
+	c := contextWithVariables(
+		Variable{Name: "id", Value: "12"},
+		Variable{Name: "bad", Value: "abc"},
+	)
+
+	if result, err := VariableInt(c, "id"); result != 12 || err != nil {
+		t.Errorf("VariableInt(id) = %v, %v WANT 12, <nil>", result, err)
+	}
+	if result, err := VariableInt64(c, "id"); result != 12 || err != nil {
+		t.Errorf("VariableInt64(id) = %v, %v WANT 12, <nil>", result, err)
+	}
+
+	_, err := VariableInt(c, "bad")
+	errIV, ok := err.(*ErrInvalidVariable)
+	if !ok || errIV.Variable != "bad" || errIV.Value != "abc" {
+		t.Errorf("VariableInt(bad) error = %#v WANT *ErrInvalidVariable", err)
+	}
+
+	if _, err := VariableInt(c, "missing"); err != ErrMissingVariable("missing") {
+		t.Errorf("VariableInt(missing) error = %v WANT %v", err, ErrMissingVariable("missing"))
+	}
+}
+
+func TestVariableUUID_parsesTheCanonicalForm(t *testing.T) {
+	const value = "123e4567-E89B-12d3-a456-426614174000"
+	c := contextWithVariables(Variable{Name: "id", Value: value}, Variable{Name: "bad", Value: "123e4567"})
+
+	result, err := VariableUUID(c, "id")
+	want := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	if result != want || err != nil {
+		t.Errorf("VariableUUID(id) = %x, %v WANT %x, <nil>", result, err, want)
+	}
+
+	if _, err := VariableUUID(c, "bad"); err == nil {
+		t.Errorf("VariableUUID(bad) error = nil WANT error")
+	}
+}
+
+func TestVariableTime_parsesWithLayout(t *testing.T) {
+	c := contextWithVariables(Variable{Name: "day", Value: "2017-03-04"})
+
+	result, err := VariableTime(c, "day", "2006-01-02")
+	if want := time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC); !result.Equal(want) || err != nil {
+		t.Errorf("VariableTime() = %v, %v WANT %v, <nil>", result, err, want)
+	}
+}
+
+func TestBindVariables_setsTaggedFields(t *testing.T) {
+	type target struct {
+		ID      int64     `httpmux:"id"`
+		Name    string    `httpmux:"name"`
+		Owner   [16]byte  `httpmux:"owner"`
+		Day     time.Time `httpmux:"day,layout=2006-01-02"`
+		Ratio   float64   `httpmux:"ratio"`
+		Missing string    `httpmux:"missing"`
+		Ignored string
+		Skipped string `httpmux:"-"`
+	}
+
+	c := contextWithVariables(
+		Variable{Name: "id", Value: "7"},
+		Variable{Name: "name", Value: "bob"},
+		Variable{Name: "owner", Value: "123e4567-e89b-12d3-a456-426614174000"},
+		Variable{Name: "day", Value: "2017-03-04"},
+		Variable{Name: "ratio", Value: "0.5"},
+		Variable{Name: "Ignored", Value: "value"},
+		Variable{Name: "-", Value: "value"},
+	)
+
+	dst := target{Missing: "unchanged"}
+	if err := BindVariables(c, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	owner, _ := parseUUID("123e4567-e89b-12d3-a456-426614174000")
+	want := target{
+		ID:      7,
+		Name:    "bob",
+		Owner:   owner,
+		Day:     time.Date(2017, 3, 4, 0, 0, 0, 0, time.UTC),
+		Ratio:   0.5,
+		Missing: "unchanged",
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("dst = %+v WANT %+v", dst, want)
+	}
+}
+
+func TestBindVariables_returnsEveryFailure(t *testing.T) {
+	var dst struct {
+		ID    int      `httpmux:"id"`
+		Small int8     `httpmux:"small"`
+		Flag  bool     `httpmux:"flag"`
+		Owner [16]byte `httpmux:"owner"`
+	}
+
+	c := contextWithVariables(
+		Variable{Name: "id", Value: "abc"},
+		Variable{Name: "small", Value: "300"},
+		Variable{Name: "flag", Value: "true"},
+		Variable{Name: "owner", Value: "nope"},
+	)
+
+	err := BindVariables(c, &dst)
+	errs, ok := err.(ErrBindVariables)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("err = %v WANT ErrBindVariables of 3", err)
+	}
+	for i, name := range []VarName{"id", "small", "owner"} {
+		if errs[i].Variable != name {
+			t.Errorf("errs[%v].Variable = %v WANT %v", i, errs[i].Variable, name)
+		}
+	}
+	if !dst.Flag {
+		t.Errorf("dst.Flag = false WANT true")
+	}
+
+	var errIV *ErrInvalidVariable
+	if !errors.As(err, &errIV) || errIV.Variable != "id" {
+		t.Errorf("errors.As() = %v WANT the id error", errIV)
+	}
+}
+
+func TestBindVariables_returnsErrInvalidBindTarget(t *testing.T) {
+	var s struct{}
+	for _, dst := range []interface{}{nil, s, (*struct{})(nil), new(int)} {
+		if _, ok := BindVariables(context.Background(), dst).(*ErrInvalidBindTarget); !ok {
+			t.Errorf("BindVariables(%#v) did not return an *ErrInvalidBindTarget", dst)
+		}
+	}
+}
+
+func TestBindVariables_returnsErrInvalidBindTag(t *testing.T) {
+	c := contextWithVariables(Variable{Name: "id", Value: "1"})
+
+	var omitEmpty struct {
+		Name string `httpmux:"name"`
+		ID   string `httpmux:"id,omitempty"`
+	}
+	var noName struct {
+		Day time.Time `httpmux:",layout=2006-01-02"`
+	}
+	tests := []struct {
+		dst   interface{}
+		field string
+	}{
+		{&omitEmpty, "ID"},
+		{&noName, "Day"},
+	}
+	for _, test := range tests {
+		err := BindVariables(c, test.dst)
+		if invalid, ok := err.(*ErrInvalidBindTag); !ok || invalid.Field != test.field {
+			t.Errorf("BindVariables(%T) = %v WANT *ErrInvalidBindTag for %v", test.dst, err, test.field)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package httpmux
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+//treeGen is the generation of the most recently cloned routeTree.
+var treeGen uint64
+
+//clone returns a copy of tree that may be modified without affecting tree.
+//
+//Only the roots of tree are copied. Every other node is shared until it is
+//about to be modified, when it is copied along with the path to it, see
+//ownStatic. So a registration only copies the nodes along its path.
+func (tree *routeTree) clone() *routeTree {
+	gen := atomic.AddUint64(&treeGen, 1)
+	result := &routeTree{
+		gen:   gen,
+		root:  ownStatic(tree.root, gen),
+		hosts: make([]*hostRoute, len(tree.hosts)),
+	}
+	for i, h := range tree.hosts {
+		result.hosts[i] = &hostRoute{pattern: h.pattern, labels: h.labels, root: ownStatic(h.root, gen)}
+	}
+	return result
+}
+
+//ownAll copies every node of tree that is not already owned by it, so that any
+//node may be modified.
+func (tree *routeTree) ownAll() {
+	ownDescendants(tree.root, tree.gen)
+	for _, h := range tree.hosts {
+		ownDescendants(h.root, tree.gen)
+	}
+}
+
+//ownStatic returns n if it is owned by the tree of generation gen, and
+//otherwise a copy of n that is. The copy shares n's children, so the caller
+//must replace n with it in n's parent, which must already be owned.
+func ownStatic(n *staticNode, gen uint64) *staticNode {
+	if n.gen == gen {
+		return n
+	}
+	result := &staticNode{
+		value:           n.value,
+		segmentVarChild: n.segmentVarChild,
+		endVarChild:     n.endVarChild,
+		methodHandler:   n.methodHandler.clone(),
+		attributes:      n.attributes.clone(gen),
+	}
+	if len(n.staticChildren) > 0 {
+		result.staticChildren = append(make([]*staticNode, 0, len(n.staticChildren)), n.staticChildren...)
+	}
+	return result
+}
+
+func ownSegmentVar(n *segmentVarNode, gen uint64) *segmentVarNode {
+	if n.gen == gen {
+		return n
+	}
+	return &segmentVarNode{
+		name:          n.name,
+		constraint:    n.constraint,
+		staticChild:   n.staticChild,
+		methodHandler: n.methodHandler.clone(),
+		attributes:    n.attributes.clone(gen),
+	}
+}
+
+func ownEndVar(n *endVarNode, gen uint64) *endVarNode {
+	if n.gen == gen {
+		return n
+	}
+	return &endVarNode{
+		name:          n.name,
+		constraint:    n.constraint,
+		methodHandler: n.methodHandler.clone(),
+		attributes:    n.attributes.clone(gen),
+	}
+}
+
+//ownDescendants makes every descendant of n, which must be owned by the tree
+//of generation gen, owned by it too. It is used before changing what the
+//descendants inherit from n.
+func ownDescendants(n node, gen uint64) {
+	switch n := n.(type) {
+	case *staticNode:
+		for i, child := range n.staticChildren {
+			n.staticChildren[i] = ownStatic(child, gen)
+			ownDescendants(n.staticChildren[i], gen)
+		}
+		if n.segmentVarChild != nil {
+			n.segmentVarChild = ownSegmentVar(n.segmentVarChild, gen)
+			ownDescendants(n.segmentVarChild, gen)
+		}
+		if n.endVarChild != nil {
+			n.endVarChild = ownEndVar(n.endVarChild, gen)
+		}
+
+	case *segmentVarNode:
+		if n.staticChild != nil {
+			n.staticChild = ownStatic(n.staticChild, gen)
+			ownDescendants(n.staticChild, gen)
+		}
+	}
+}
+
+func (mh methodHandler) clone() methodHandler {
+	if mh.methods == nil {
+		return mh
+	}
+	methods := make(map[string]http.Handler, len(mh.methods))
+	for method, handler := range mh.methods {
+		methods[method] = handler
+	}
+	mh.methods = methods
+	return mh
+}
+
+//clone returns a copy of a owned by the tree of generation gen. Metadata is
+//shared since it is replaced instead of modified.
+func (a attributes) clone(gen uint64) attributes {
+	if len(a.middleware) > 0 {
+		a.middleware = append(make([]Middleware, 0, len(a.middleware)), a.middleware...)
+	}
+	a.gen = gen
+	return a
+}
@@ -0,0 +1,349 @@
+//Package config builds httpmux.Muxes from routes declared in JSON or YAML.
+//
+//A config declares routes and the names of their handlers and middleware,
+//which are looked up in a Registry of Go values:
+//
+//	middleware: [logging]
+//	routes:
+//	  - pattern: /users/:id{int}
+//	    methods: [GET, PUT]
+//	    handler: user
+//	    middleware: [auth]
+//	    name: user
+//	  - host: "{tenant}.example.com"
+//	    pattern: /files/*path
+//	    handler: files
+//
+//The equivalent JSON is an object with the same keys.
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gogolfing/httpmux"
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//Format is the format of a config.
+type Format int
+
+const (
+	JSON Format = iota
+	YAML
+)
+
+//FormatOf returns the Format of the file name by its extension. Files with
+//the extensions .yaml and .yml are YAML, and all others are JSON.
+func FormatOf(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return YAML
+	}
+	return JSON
+}
+
+//Config is a parsed config.
+type Config struct {
+	//Middleware are the names of middleware added to the Mux with Mux.Use.
+	Middleware []string
+
+	Routes []*Route
+}
+
+//Route is a route declared in a config.
+type Route struct {
+	//Line is the line of the config that the route starts on.
+	Line int
+
+	//Host is the host pattern of the route, see httpmux.Mux.Host. It is
+	//optional.
+	Host string
+
+	//Pattern is the path pattern of the route. It is required.
+	Pattern string
+
+	//Methods are the methods the handler is registered for, or all methods if
+	//there are none.
+	Methods []string
+
+	//Handler is the name of the handler. It is required.
+	Handler string
+
+	//Middleware are the names of middleware that wrap the handler, outermost
+	//first. They only wrap this route's handler.
+	Middleware []string
+
+	//Name is the name the route is registered under with httpmux.Route.Name.
+	//It is optional.
+	Name string
+}
+
+//Registry holds the handlers and middleware that configs refer to by name.
+type Registry struct {
+	Handlers   map[string]http.Handler
+	Middleware map[string]httpmux.Middleware
+}
+
+//LoadFile is the same as Load with the contents and Format of the file name.
+func LoadFile(name string, registry *Registry) (*httpmux.Mux, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data, FormatOf(name), registry)
+}
+
+//Load returns a new Mux with the routes of the config data registered on it.
+//It returns an ErrLoad with every error in the config instead of a Mux if the
+//config cannot be parsed or any of its routes cannot be registered.
+func Load(data []byte, format Format, registry *Registry) (*httpmux.Mux, error) {
+	c, err := Parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+	m := httpmux.New()
+	if err := c.Register(m, registry); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//Parse parses data in format into a Config.
+//It returns an ErrLoad with an *ErrInvalidConfig for every error found.
+//
+//YAML is limited to block mappings and lists indented with spaces, plain and
+//quoted scalars, ~ and null, flow lists of scalars, the empty flow mapping {},
+//comments, and ---. Anything else, e.g. tabs in indentation, other flow
+//mappings, anchors, aliases, tags, block scalars, directives, and duplicate
+//keys, is an error. Scalars that start with one of "[{&*!|>%@`" must be quoted.
+func Parse(data []byte, format Format) (*Config, error) {
+	var root *value
+	var err error
+	if format == YAML {
+		root, err = parseYAML(data)
+	} else {
+		root, err = parseJSON(data)
+	}
+	if err != nil {
+		return nil, ErrLoad{err.(*ErrInvalidConfig)}
+	}
+
+	d := &decoder{}
+	c := d.config(root)
+	if len(d.errs) > 0 {
+		return nil, d.errs
+	}
+	return c, nil
+}
+
+//Register registers the routes of c on m using the handlers and middleware in
+//registry. Routes are registered with TrySubRoute, so that every route is
+//validated the same way as routes registered in code.
+//
+//Routes that cannot be registered are skipped, and an ErrLoad is returned with
+//an *ErrInvalidConfig for each of them after the others are registered.
+func (c *Config) Register(m *httpmux.Mux, registry *Registry) error {
+	errs := ErrLoad{}
+
+	middleware := []httpmux.Middleware{}
+	for _, name := range c.Middleware {
+		mw, ok := registry.Middleware[name]
+		if !ok {
+			errs = append(errs, &ErrInvalidConfig{Err: ErrUnknownMiddleware(name)})
+			continue
+		}
+		middleware = append(middleware, mw)
+	}
+
+	registered := []*Route{}
+	for _, route := range c.Routes {
+		if err := route.register(m, registry); err != nil {
+			errs = append(errs, newErrInvalidConfig(route, registered, err))
+			continue
+		}
+		registered = append(registered, route)
+	}
+
+	m.Use(middleware...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (route *Route) register(m *httpmux.Mux, registry *Registry) error {
+	handler, ok := registry.Handlers[route.Handler]
+	if !ok {
+		return ErrUnknownHandler(route.Handler)
+	}
+	for i := len(route.Middleware) - 1; i >= 0; i-- {
+		mw, ok := registry.Middleware[route.Middleware[i]]
+		if !ok {
+			return ErrUnknownMiddleware(route.Middleware[i])
+		}
+		handler = mw(handler)
+	}
+
+	root := m.Root()
+	if len(route.Host) > 0 {
+		root = m.Host(route.Host)
+	}
+	r, err := root.TrySubRoute(route.Pattern)
+	if err != nil {
+		return err
+	}
+	r.Handle(handler, route.Methods...)
+	if len(route.Name) > 0 {
+		r.Name(route.Name)
+	}
+	return nil
+}
+
+//newErrInvalidConfig returns the error for route failing to register with
+//err, including the line of the route in registered that err conflicts with if
+//there is one.
+func newErrInvalidConfig(route *Route, registered []*Route, err error) *ErrInvalidConfig {
+	result := &ErrInvalidConfig{Line: route.Line, Err: err}
+
+	invalid, ok := err.(*httpmux.ErrInvalidRoute)
+	if !ok || len(invalid.ConflictingPattern) == 0 {
+		return result
+	}
+	for _, other := range registered {
+		if other.Host == route.Host && other.expandsInto(invalid.ConflictingPattern) {
+			result.ConflictingLine = other.Line
+			break
+		}
+	}
+	return result
+}
+
+//expandsInto returns whether pattern is one of the cleaned patterns that
+//route's pattern expands into.
+func (route *Route) expandsInto(pattern string) bool {
+	patterns, _ := muxpath.ExpandOptionalParts(route.Pattern)
+	for _, expanded := range patterns {
+		if muxpath.CleanPattern(expanded) == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+//decoder decodes values into a Config and collects every error found.
+type decoder struct {
+	errs ErrLoad
+}
+
+func (d *decoder) errorf(line int, format string, args ...interface{}) {
+	d.errs = append(d.errs, &ErrInvalidConfig{Line: line, Err: fmt.Errorf(format, args...)})
+}
+
+func (d *decoder) expectKind(v *value, kind valueKind, key string) bool {
+	if v.kind != kind {
+		d.errorf(v.line, "%s must be %v, not %v", key, kind, v.kind)
+		return false
+	}
+	return true
+}
+
+func (d *decoder) config(root *value) *Config {
+	c := &Config{}
+	if root.kind == kindNull {
+		return c
+	}
+	if !d.expectKind(root, kindMap, "config") {
+		return c
+	}
+
+	for _, key := range root.keys {
+		v := root.fields[key]
+		switch key {
+		case "middleware":
+			c.Middleware = d.strings(v, key)
+		case "routes":
+			c.Routes = d.routes(v)
+		default:
+			d.errorf(v.line, "unknown key %q", key)
+		}
+	}
+	return c
+}
+
+func (d *decoder) routes(v *value) []*Route {
+	if v.kind == kindNull || !d.expectKind(v, kindList, "routes") {
+		return nil
+	}
+	result := []*Route{}
+	for _, item := range v.list {
+		if route := d.route(item); route != nil {
+			result = append(result, route)
+		}
+	}
+	return result
+}
+
+func (d *decoder) route(v *value) *Route {
+	if !d.expectKind(v, kindMap, "route") {
+		return nil
+	}
+
+	errCount := len(d.errs)
+	route := &Route{Line: v.line}
+	for _, key := range v.keys {
+		field := v.fields[key]
+		switch key {
+		case "host":
+			route.Host = d.string(field, key)
+		case "pattern":
+			route.Pattern = d.string(field, key)
+		case "methods":
+			route.Methods = d.strings(field, key)
+		case "handler":
+			route.Handler = d.string(field, key)
+		case "middleware":
+			route.Middleware = d.strings(field, key)
+		case "name":
+			route.Name = d.string(field, key)
+		default:
+			d.errorf(field.line, "unknown key %q", key)
+		}
+	}
+
+	if _, ok := v.fields["pattern"]; !ok {
+		d.errorf(v.line, "route is missing %q", "pattern")
+	}
+	if _, ok := v.fields["handler"]; !ok {
+		d.errorf(v.line, "route is missing %q", "handler")
+	}
+	if len(d.errs) > errCount {
+		return nil
+	}
+	return route
+}
+
+func (d *decoder) string(v *value, key string) string {
+	if !d.expectKind(v, kindScalar, key) {
+		return ""
+	}
+	return v.scalar
+}
+
+func (d *decoder) strings(v *value, key string) []string {
+	if v.kind == kindNull || !d.expectKind(v, kindList, key) {
+		return nil
+	}
+	result := []string{}
+	for _, item := range v.list {
+		if item.kind != kindScalar {
+			d.errorf(item.line, "%s must be a list of scalars", key)
+			continue
+		}
+		result = append(result, item.scalar)
+	}
+	return result
+}
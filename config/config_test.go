@@ -0,0 +1,336 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogolfing/httpmux"
+)
+
+func textHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body+" "+httpmux.VariableValue(r.Context(), "id"))
+	})
+}
+
+func headerMiddleware(value string) httpmux.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Middleware", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newTestRegistry() *Registry {
+	return &Registry{
+		Handlers: map[string]http.Handler{
+			"users": textHandler("users"),
+			"user":  textHandler("user"),
+			"files": textHandler("files"),
+		},
+		Middleware: map[string]httpmux.Middleware{
+			"logging": headerMiddleware("logging"),
+			"auth":    headerMiddleware("auth"),
+		},
+	}
+}
+
+const testYAML = `# Routes of the test API.
+middleware: [logging]
+routes:
+  - pattern: /users
+    methods: [GET]
+    handler: users
+  - pattern: "/users/:id{int}"
+    methods:
+      - GET
+      - PUT
+    handler: user
+    middleware: [auth]
+    name: user
+  - host: '{tenant}.example.com'
+    pattern: /files/*path # Every file.
+    handler: files
+`
+
+const testJSON = `{
+  "middleware": ["logging"],
+  "routes": [
+    {"pattern": "/users", "methods": ["GET"], "handler": "users"},
+    {
+      "pattern": "/users/:id{int}",
+      "methods": ["GET", "PUT"],
+      "handler": "user",
+      "middleware": ["auth"],
+      "name": "user"
+    },
+    {"host": "{tenant}.example.com", "pattern": "/files/*path", "handler": "files"}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	want := &Config{
+		Middleware: []string{"logging"},
+		Routes: []*Route{
+			{Line: 4, Pattern: "/users", Methods: []string{"GET"}, Handler: "users"},
+			{
+				Line:       7,
+				Pattern:    "/users/:id{int}",
+				Methods:    []string{"GET", "PUT"},
+				Handler:    "user",
+				Middleware: []string{"auth"},
+				Name:       "user",
+			},
+			{Line: 14, Host: "{tenant}.example.com", Pattern: "/files/*path", Handler: "files"},
+		},
+	}
+
+	c, err := Parse([]byte(testYAML), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Parse(YAML) = %v WANT %v", c, want)
+	}
+
+	want.Routes[0].Line, want.Routes[1].Line, want.Routes[2].Line = 4, 5, 12
+	c, err = Parse([]byte(testJSON), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("Parse(JSON) = %v WANT %v", c, want)
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		data   string
+		format Format
+		lines  []int
+	}{
+		{`[]`, JSON, []int{1}},
+		{"{\n  \"routes\": [\n    {\"pattern\": \"/\"\n  ]\n}", JSON, []int{4}},
+		{"{\"routes\": [{\"pattern\": \"/\", \"pattern\": \"/a\"}]}", JSON, []int{1}},
+		{
+			"routes:\n  - pattern: /\n    handler: a\n    other: b\n  - handler: b\n  - pattern: [a]\n    handler: c\n",
+			YAML,
+			[]int{4, 5, 6},
+		},
+		{"routes:\n  - pattern: /\n     handler: a\n", YAML, []int{3}},
+		{"routes:\n\t- pattern: /\n", YAML, []int{2}},
+		{"routes: &anchor\n", YAML, []int{1}},
+		{"routes:\n  - pattern: /\n    handler: a\n    methods: [GET, [POST]]\n", YAML, []int{4}},
+		{"unknown: 1\nmiddleware: a\n", YAML, []int{1, 2}},
+	}
+
+	for i, test := range tests {
+		_, err := Parse([]byte(test.data), test.format)
+		errs, ok := err.(ErrLoad)
+		if !ok {
+			t.Errorf("%d: Parse() error = %v WANT ErrLoad", i, err)
+			continue
+		}
+		lines := []int{}
+		for _, err := range errs {
+			lines = append(lines, err.Line)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%d: Parse() error lines = %v WANT %v (%v)", i, lines, test.lines, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		host       string
+		method     string
+		path       string
+		status     int
+		body       string
+		middleware []string
+	}{
+		{"", "GET", "/users", http.StatusOK, "users ", []string{"logging"}},
+		{"", "POST", "/users", http.StatusMethodNotAllowed, "Method Not Allowed\n", []string{"logging"}},
+		{"", "PUT", "/users/12", http.StatusOK, "user 12", []string{"logging", "auth"}},
+		{"", "GET", "/users/me", http.StatusNotFound, "Not Found\n", []string{"logging"}},
+		{"a.example.com", "GET", "/files/a/b", http.StatusOK, "files ", []string{"logging"}},
+		{"", "GET", "/files/a/b", http.StatusNotFound, "Not Found\n", []string{"logging"}},
+	}
+
+	for _, format := range []Format{YAML, JSON} {
+		data := testYAML
+		if format == JSON {
+			data = testJSON
+		}
+		m, err := Load([]byte(data), format, newTestRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, test := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(test.method, test.path, nil)
+			if len(test.host) > 0 {
+				r.Host = test.host
+			}
+			m.ServeHTTP(w, r)
+
+			if w.Code != test.status || w.Body.String() != test.body {
+				t.Errorf("%v %d: %d %q WANT %d %q", format, i, w.Code, w.Body.String(), test.status, test.body)
+			}
+			if middleware := w.Header()["X-Middleware"]; !reflect.DeepEqual(middleware, test.middleware) {
+				t.Errorf("%v %d: middleware = %v WANT %v", format, i, middleware, test.middleware)
+			}
+		}
+
+		if url, err := m.URL("user", &httpmux.Variable{Name: "id", Value: "3"}); err != nil || url != "/users/3" {
+			t.Errorf("%v: URL() = %q, %v WANT %q, nil", format, url, err, "/users/3")
+		}
+	}
+}
+
+func TestLoad_errors(t *testing.T) {
+	data := `middleware: [missing]
+routes:
+  - pattern: /users/:id
+    handler: user
+  - pattern: /users/:name/keys
+    handler: user
+  - pattern: /users/:id/:key:other
+    handler: user
+  - pattern: /files
+    handler: missing
+  - pattern: /docs[/:page
+    handler: user
+  - pattern: /files
+    handler: files
+    middleware: [auth, missing]
+  - pattern: /users/:id{int}/keys
+    handler: user
+  - pattern: /a/:id/xyz
+    handler: user
+  - pattern: /a/:id/x
+    handler: user
+  - pattern: /a/:name
+    handler: user
+`
+	_, err := Load([]byte(data), YAML, newTestRegistry())
+
+	errs, ok := err.(ErrLoad)
+	if !ok {
+		t.Fatalf("Load() error = %v WANT ErrLoad", err)
+	}
+
+	type result struct {
+		line, conflictingLine int
+	}
+	want := []result{{0, 0}, {5, 3}, {7, 0}, {9, 0}, {11, 0}, {13, 0}, {16, 3}, {22, 20}}
+	results := []result{}
+	for _, err := range errs {
+		results = append(results, result{err.Line, err.ConflictingLine})
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("Load() errors = %v WANT %v\n%v", results, want, err)
+	}
+
+	var unequalVars *httpmux.ErrUnequalVars
+	if !errors.As(errs[1], &unequalVars) {
+		t.Errorf("errs[1] = %v WANT *httpmux.ErrUnequalVars", errs[1])
+	}
+	var unequalConstraints *httpmux.ErrUnequalConstraints
+	if !errors.As(errs[6], &unequalConstraints) {
+		t.Errorf("errs[6] = %v WANT *httpmux.ErrUnequalConstraints", errs[6])
+	}
+	if !errors.Is(err, ErrUnknownHandler("missing")) || !errors.Is(err, ErrUnknownMiddleware("missing")) {
+		t.Errorf("Load() error = %v WANT unknown handler and middleware", err)
+	}
+	if message := errs[1].Error(); !strings.HasPrefix(message, "httpmux/config: line 5: ") || !strings.HasSuffix(message, "(see line 3)") {
+		t.Errorf("errs[1].Error() = %q", message)
+	}
+}
+
+func TestConfig_Register_invalidRoutesDoNotConflictWithLaterRoutes(t *testing.T) {
+	data := `routes:
+  - pattern: /docs/:page[/:section
+    handler: user
+  - pattern: /users/:id/:key:other
+    handler: user
+  - pattern: /docs/:name
+    handler: user
+  - pattern: /users/:name/:key
+    handler: user
+`
+	c, err := Parse([]byte(data), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := httpmux.New()
+	err = c.Register(m, newTestRegistry())
+
+	errs, ok := err.(ErrLoad)
+	if !ok || len(errs) != 2 || errs[0].Line != 2 || errs[1].Line != 4 {
+		t.Fatalf("Register() error = %v WANT errors at lines 2 and 4", err)
+	}
+	for _, err := range errs {
+		var invalid *httpmux.ErrInvalidRoute
+		if errors.As(err, &invalid) && len(invalid.ConflictingPattern) > 0 {
+			t.Errorf("Register() error = %v WANT no conflict", err)
+		}
+	}
+
+	for _, path := range []string{"/docs/a", "/users/a/b"} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %v = %v WANT %v", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "routes.yml")
+	if err := os.WriteFile(name, []byte(testYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadFile(name, newTestRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/users/4", nil))
+	if w.Body.String() != "user 4" {
+		t.Errorf("body = %q WANT %q", w.Body.String(), "user 4")
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "missing.json"), newTestRegistry()); !os.IsNotExist(err) {
+		t.Errorf("LoadFile() error = %v WANT not exist", err)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{"routes.json", JSON},
+		{"routes.yaml", YAML},
+		{"ROUTES.YML", YAML},
+		{"routes", JSON},
+	}
+	for _, test := range tests {
+		if format := FormatOf(test.name); format != test.format {
+			t.Errorf("FormatOf(%q) = %v WANT %v", test.name, format, test.format)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ErrInvalidConfig is an error at a line of a config.
+type ErrInvalidConfig struct {
+	//Line is the line of the config the error is at, or 0 if the error is not
+	//at a single line.
+	Line int
+
+	//ConflictingLine is the line of a previously registered route that the
+	//route at Line conflicts with, or 0 if there is none.
+	ConflictingLine int
+
+	//Err is the underlying error, e.g. an *httpmux.ErrInvalidRoute or an
+	//ErrUnknownHandler.
+	Err error
+}
+
+func (e *ErrInvalidConfig) Error() string {
+	message := e.Err.Error()
+	if e.ConflictingLine > 0 {
+		message = fmt.Sprintf("%v (see line %d)", message, e.ConflictingLine)
+	}
+	if e.Line == 0 {
+		return "httpmux/config: " + message
+	}
+	return fmt.Sprintf("httpmux/config: line %d: %v", e.Line, message)
+}
+
+func (e *ErrInvalidConfig) Unwrap() error {
+	return e.Err
+}
+
+//ErrLoad is the error returned with every *ErrInvalidConfig found while
+//parsing or registering a config.
+type ErrLoad []*ErrInvalidConfig
+
+func (e ErrLoad) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e ErrLoad) Unwrap() []error {
+	result := make([]error, len(e))
+	for i, err := range e {
+		result[i] = err
+	}
+	return result
+}
+
+//ErrUnknownHandler is the error used when a config refers to a handler that is
+//not in the Registry.
+type ErrUnknownHandler string
+
+func (e ErrUnknownHandler) Error() string {
+	return fmt.Sprintf("unknown handler %q", string(e))
+}
+
+//ErrUnknownMiddleware is the error used when a config refers to middleware
+//that is not in the Registry.
+type ErrUnknownMiddleware string
+
+func (e ErrUnknownMiddleware) Error() string {
+	return fmt.Sprintf("unknown middleware %q", string(e))
+}
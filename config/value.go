@@ -0,0 +1,179 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type valueKind int
+
+const (
+	kindNull valueKind = iota
+	kindScalar
+	kindList
+	kindMap
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindScalar:
+		return "a scalar"
+	case kindList:
+		return "a list"
+	case kindMap:
+		return "a mapping"
+	}
+	return "null"
+}
+
+//value is a decoded JSON or YAML value along with the line it starts on.
+type value struct {
+	line int
+	kind valueKind
+
+	scalar string
+	list   []*value
+
+	//keys are the keys of fields in the order they appear.
+	keys   []string
+	fields map[string]*value
+}
+
+//field is a key of a mapping and its value.
+type field struct {
+	line  int
+	key   string
+	value *value
+}
+
+func newMap(line int) *value {
+	return &value{line: line, kind: kindMap, fields: map[string]*value{}}
+}
+
+//set adds f to v and returns an error if f's key is already in v.
+func (v *value) set(f field) error {
+	if _, ok := v.fields[f.key]; ok {
+		return &ErrInvalidConfig{Line: f.line, Err: fmt.Errorf("duplicate key %q", f.key)}
+	}
+	v.keys = append(v.keys, f.key)
+	v.fields[f.key] = f.value
+	return nil
+}
+
+//lineAt returns the line number of the first byte at or after offset in data
+//that is not whitespace or a JSON separator.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+//jsonParser decodes JSON into values while tracking line numbers.
+type jsonParser struct {
+	data    []byte
+	decoder *json.Decoder
+}
+
+func parseJSON(data []byte) (*value, error) {
+	p := &jsonParser{data: data, decoder: json.NewDecoder(bytes.NewReader(data))}
+	p.decoder.UseNumber()
+
+	result, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.decoder.Token(); err != io.EOF {
+		return nil, &ErrInvalidConfig{Line: p.line(), Err: fmt.Errorf("unexpected data after top-level value")}
+	}
+	return result, nil
+}
+
+func (p *jsonParser) line() int {
+	return lineAt(p.data, p.decoder.InputOffset())
+}
+
+func (p *jsonParser) token() (json.Token, error) {
+	token, err := p.decoder.Token()
+	if err == nil {
+		return token, nil
+	}
+	if syntaxErr, ok := err.(*json.SyntaxError); ok {
+		//Offset is just after the invalid byte.
+		offset := syntaxErr.Offset - 1
+		if offset < 0 {
+			offset = 0
+		}
+		line := bytes.Count(p.data[:offset], []byte("\n")) + 1
+		return nil, &ErrInvalidConfig{Line: line, Err: err}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, &ErrInvalidConfig{Line: p.line(), Err: err}
+}
+
+func (p *jsonParser) parse() (*value, error) {
+	line := p.line()
+	token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			return p.parseList(line)
+		}
+		return p.parseMap(line)
+
+	case string:
+		return &value{line: line, kind: kindScalar, scalar: token}, nil
+
+	case json.Number:
+		return &value{line: line, kind: kindScalar, scalar: token.String()}, nil
+
+	case bool:
+		return &value{line: line, kind: kindScalar, scalar: fmt.Sprint(token)}, nil
+	}
+	return &value{line: line, kind: kindNull}, nil
+}
+
+func (p *jsonParser) parseList(line int) (*value, error) {
+	result := &value{line: line, kind: kindList}
+	for p.decoder.More() {
+		item, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		result.list = append(result.list, item)
+	}
+	_, err := p.token()
+	return result, err
+}
+
+func (p *jsonParser) parseMap(line int) (*value, error) {
+	result := newMap(line)
+	for p.decoder.More() {
+		keyLine := p.line()
+		key, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		item, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		if err := result.set(field{line: keyLine, key: key.(string), value: item}); err != nil {
+			return nil, err
+		}
+	}
+	_, err := p.token()
+	return result, err
+}
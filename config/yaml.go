@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/gogolfing/httpmux/internal/yaml"
+)
+
+func parseYAML(data []byte) (*value, error) {
+	node, err := yaml.Parse(data)
+	if err != nil {
+		syntaxErr := err.(*yaml.SyntaxError)
+		return nil, &ErrInvalidConfig{Line: syntaxErr.Line, Err: syntaxErr.Err}
+	}
+	return newYAMLValue(node), nil
+}
+
+//newYAMLValue converts node and its children into values.
+func newYAMLValue(node *yaml.Node) *value {
+	switch node.Kind {
+	case yaml.Scalar:
+		return &value{line: node.Line, kind: kindScalar, scalar: node.Scalar}
+
+	case yaml.List:
+		result := &value{line: node.Line, kind: kindList}
+		for _, item := range node.List {
+			result.list = append(result.list, newYAMLValue(item))
+		}
+		return result
+
+	case yaml.Map:
+		result := newMap(node.Line)
+		result.keys = node.Keys
+		for key, field := range node.Fields {
+			result.fields[key] = newYAMLValue(field)
+		}
+		return result
+	}
+	return &value{line: node.Line, kind: kindNull}
+}
@@ -0,0 +1,89 @@
+package httpmux
+
+import "regexp"
+
+const (
+	ConstraintInt  = "int"
+	ConstraintUUID = "uuid"
+)
+
+//constraint restricts the values a variable may match.
+//A nil *constraint matches every value.
+type constraint struct {
+	source  string
+	matches func(value string) bool
+}
+
+//newConstraint returns the constraint described by source.
+//source is either one of the named constraints, ConstraintInt and
+//ConstraintUUID, or a regular expression that must match an entire value.
+func newConstraint(name VarName, source string) (*constraint, error) {
+	if len(source) == 0 {
+		return nil, nil
+	}
+	switch source {
+	case ConstraintInt:
+		return &constraint{source: source, matches: isInt}, nil
+	case ConstraintUUID:
+		return &constraint{source: source, matches: isUUID}, nil
+	}
+	re, err := regexp.Compile(`^(?:` + source + `)$`)
+	if err != nil {
+		return nil, &ErrInvalidConstraint{Variable: name, Constraint: source, Err: err}
+	}
+	return &constraint{source: source, matches: re.MatchString}, nil
+}
+
+func (c *constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.source
+}
+
+func (c *constraint) match(value string) bool {
+	return c == nil || c.matches(value)
+}
+
+func (c *constraint) equals(other *constraint) bool {
+	return c.String() == other.String()
+}
+
+func isInt(value string) bool {
+	if len(value) > 0 && (value[0] == '-' || value[0] == '+') {
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//isUUID returns whether value is in the canonical 8-4-4-4-12 hexadecimal form.
+func isUUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if value[i] != '-' {
+				return false
+			}
+		default:
+			if !isHex(value[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isHex(b byte) bool {
+	return ('0' <= b && b <= '9') || ('a' <= b && b <= 'f') || ('A' <= b && b <= 'F')
+}
@@ -0,0 +1,198 @@
+//Package debug provides an http.Handler that describes the routes registered
+//on an httpmux.Mux and explains how requests are matched against them.
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gogolfing/httpmux"
+)
+
+//Query parameters of requests to Handler.
+const (
+	//ParamPath is the path of the request to explain.
+	ParamPath = "path"
+
+	//ParamMethod is the method of the request to explain. It defaults to GET.
+	ParamMethod = "method"
+
+	//ParamHost is the host of the request to explain. It defaults to the host
+	//of the request to Handler.
+	ParamHost = "host"
+
+	//ParamFormat is "json" or "html" and overrides the Accept header.
+	ParamFormat = "format"
+)
+
+//Handler returns an http.Handler that renders the routes registered on m as
+//HTML, or as JSON if requested with the Accept header or the ParamFormat query
+//parameter.
+//
+//If the ParamPath query parameter is given, then the handler also explains
+//each step of searching m for a request with that path, see
+//httpmux.Mux.Explain.
+//
+//The handler may be registered on m itself, e.g. at "/debug/routes", but
+//exposes the structure of m and should not be publicly accessible.
+func Handler(m *httpmux.Mux) http.Handler {
+	return &handler{mux: m}
+}
+
+type handler struct {
+	mux *httpmux.Mux
+}
+
+type response struct {
+	Routes      []*route     `json:"routes"`
+	Explanation *explanation `json:"explanation,omitempty"`
+}
+
+type route struct {
+	Host       string   `json:"host,omitempty"`
+	Pattern    string   `json:"pattern"`
+	Methods    []string `json:"methods"`
+	AllMethods bool     `json:"allMethods"`
+}
+
+type explanation struct {
+	Method    string      `json:"method"`
+	Host      string      `json:"host"`
+	Path      string      `json:"path"`
+	Steps     []*step     `json:"steps"`
+	Status    int         `json:"status"`
+	Allow     []string    `json:"allow,omitempty"`
+	Redirect  string      `json:"redirect,omitempty"`
+	Pattern   string      `json:"pattern,omitempty"`
+	Variables []*variable `json:"variables,omitempty"`
+}
+
+type step struct {
+	Depth     int    `json:"depth"`
+	Kind      string `json:"kind"`
+	Value     string `json:"value"`
+	Remaining string `json:"remaining"`
+	Captured  string `json:"captured,omitempty"`
+	Result    string `json:"result"`
+}
+
+type variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := &response{Routes: []*route{}}
+	h.mux.Walk(func(info httpmux.RouteInfo) error {
+		result.Routes = append(result.Routes, &route{
+			Host:       info.Host,
+			Pattern:    info.Pattern,
+			Methods:    info.Methods,
+			AllMethods: info.HandlesAllMethods(),
+		})
+		return nil
+	})
+
+	query := r.URL.Query()
+	if path := query.Get(ParamPath); len(path) > 0 {
+		explained, err := h.explain(r, path, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result.Explanation = explained
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	htmlTemplate.Execute(w, &htmlData{response: result, Query: query})
+}
+
+func (h *handler) explain(r *http.Request, path string, query url.Values) (*explanation, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	target, err := url.ParseRequestURI(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: strings.ToUpper(query.Get(ParamMethod)),
+		URL:    target,
+		Host:   query.Get(ParamHost),
+		Header: http.Header{},
+	}
+	if len(req.Method) == 0 {
+		req.Method = http.MethodGet
+	}
+	if len(req.Host) == 0 {
+		req.Host = r.Host
+	}
+
+	e := h.mux.Explain(req)
+	result := &explanation{
+		Method:   req.Method,
+		Host:     req.Host,
+		Path:     e.Path,
+		Steps:    []*step{},
+		Status:   status(e),
+		Redirect: e.Redirect,
+		Pattern:  e.Route.Pattern,
+	}
+	for _, s := range e.Steps {
+		result.Steps = append(result.Steps, &step{
+			Depth:     s.Depth,
+			Kind:      s.Kind,
+			Value:     s.Value,
+			Remaining: s.Remaining,
+			Captured:  s.Captured,
+			Result:    s.Result,
+		})
+	}
+	if allow, ok := e.Err.(httpmux.ErrMethodNotAllowed); ok {
+		result.Allow = allow
+	}
+	for _, v := range e.Variables {
+		result.Variables = append(result.Variables, &variable{Name: string(v.Name), Value: v.Value})
+	}
+	return result, nil
+}
+
+//status returns the status code that the request explained by e would be
+//responded to with if its handler does not choose one.
+func status(e *httpmux.Explanation) int {
+	if len(e.Redirect) > 0 {
+		return e.RedirectStatus
+	}
+	switch err := e.Err.(type) {
+	case nil:
+		return http.StatusOK
+	case httpmux.ErrStatusHandler:
+		return int(err)
+	case httpmux.ErrMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case httpmux.ErrNotMatched:
+		return int(err)
+	}
+	return http.StatusInternalServerError
+}
+
+func wantsJSON(r *http.Request) bool {
+	switch r.URL.Query().Get(ParamFormat) {
+	case "json":
+		return true
+	case "html":
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
@@ -0,0 +1,164 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogolfing/httpmux"
+)
+
+var emptyHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func newTestMux() *httpmux.Mux {
+	m := httpmux.New()
+	m.Handle("/users/:id{int}", emptyHandler, "GET", "PUT")
+	m.Handle("/files/*path", emptyHandler)
+	m.Handle("/debug/routes", Handler(m), "GET")
+	return m
+}
+
+func serveJSON(t *testing.T, m *httpmux.Mux, target string) *response {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", target, nil)
+	r.Header.Set("Accept", "application/json")
+	m.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("%s: %d %q", target, w.Code, w.Header().Get("Content-Type"))
+	}
+	result := &response{}
+	if err := json.Unmarshal(w.Body.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestHandler_routes(t *testing.T) {
+	result := serveJSON(t, newTestMux(), "/debug/routes")
+
+	want := []*route{
+		{Pattern: "/debug/routes", Methods: []string{"GET"}},
+		{Pattern: "/files/*path", Methods: []string{}, AllMethods: true},
+		{Pattern: "/users/:id{int}", Methods: []string{"GET", "PUT"}},
+	}
+	if !reflect.DeepEqual(result.Routes, want) {
+		t.Errorf("routes = %v WANT %v", result.Routes, want)
+	}
+	if result.Explanation != nil {
+		t.Errorf("explanation = %v WANT nil", result.Explanation)
+	}
+}
+
+func TestHandler_explain(t *testing.T) {
+	tests := []struct {
+		query     string
+		status    int
+		pattern   string
+		allow     []string
+		variables []*variable
+		//step is the last step of searches that are not found, and the first
+		//step otherwise.
+		step *step
+	}{
+		{
+			query:     "path=/users/12",
+			status:    http.StatusOK,
+			pattern:   "/users/:id{int}",
+			variables: []*variable{{Name: "id", Value: "12"}},
+			step:      &step{Depth: 0, Kind: httpmux.TraceStatic, Value: "", Remaining: "/users/12", Result: httpmux.TraceFoundBeneath},
+		},
+		{
+			query:     "path=/users/12&method=delete",
+			status:    http.StatusMethodNotAllowed,
+			pattern:   "/users/:id{int}",
+			allow:     []string{"GET", "PUT"},
+			variables: []*variable{{Name: "id", Value: "12"}},
+			step:      &step{Depth: 0, Kind: httpmux.TraceStatic, Value: "", Remaining: "/users/12", Result: httpmux.TraceFoundBeneath},
+		},
+		{
+			query:  "path=users/me",
+			status: http.StatusNotFound,
+			step: &step{
+				Depth:     3,
+				Kind:      httpmux.TraceSegmentVariable,
+				Value:     ":id{int}",
+				Remaining: "me",
+				Captured:  "me",
+				Result:    httpmux.TraceConstraintNotSatisfied,
+			},
+		},
+	}
+
+	m := newTestMux()
+	for _, test := range tests {
+		e := serveJSON(t, m, "/debug/routes?"+test.query).Explanation
+		if e == nil {
+			t.Errorf("%s: explanation = nil", test.query)
+			continue
+		}
+		if e.Status != test.status || e.Pattern != test.pattern || !reflect.DeepEqual(e.Allow, test.allow) {
+			t.Errorf("%s: %d %q %v WANT %d %q %v", test.query, e.Status, e.Pattern, e.Allow, test.status, test.pattern, test.allow)
+		}
+		if !reflect.DeepEqual(e.Variables, test.variables) {
+			t.Errorf("%s: variables = %v WANT %v", test.query, e.Variables, test.variables)
+		}
+		s := e.Steps[0]
+		if test.status == http.StatusNotFound {
+			s = e.Steps[len(e.Steps)-1]
+		}
+		if !reflect.DeepEqual(s, test.step) {
+			t.Errorf("%s: step = %v WANT %v", test.query, s, test.step)
+		}
+	}
+}
+
+func TestHandler_html(t *testing.T) {
+	w := httptest.NewRecorder()
+	newTestMux().ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes?path=/users/<b>", nil))
+
+	body := w.Body.String()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"<code>/users/:id{int}</code>",
+		"responds with <strong>404</strong>",
+		"/users/&lt;b&gt;",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<b>") {
+		t.Errorf("body contains unescaped path")
+	}
+}
+
+func TestHandler_format(t *testing.T) {
+	tests := []struct {
+		target string
+		accept string
+		json   bool
+	}{
+		{"/debug/routes", "", false},
+		{"/debug/routes", "text/html,application/json", false},
+		{"/debug/routes", "application/json", true},
+		{"/debug/routes?format=json", "", true},
+		{"/debug/routes?format=html", "application/json", false},
+	}
+	m := newTestMux()
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", test.target, nil)
+		r.Header.Set("Accept", test.accept)
+		m.ServeHTTP(w, r)
+		isJSON := strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+		if isJSON != test.json {
+			t.Errorf("%s %q: JSON = %v WANT %v", test.target, test.accept, isJSON, test.json)
+		}
+	}
+}
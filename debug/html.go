@@ -0,0 +1,60 @@
+package debug
+
+import (
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/gogolfing/httpmux"
+)
+
+type htmlData struct {
+	*response
+	Query url.Values
+}
+
+var htmlTemplate = template.Must(template.New("routes").Funcs(template.FuncMap{
+	"join":   strings.Join,
+	"indent": func(depth int) float64 { return 1.5 * float64(depth) },
+	"found": func(result string) bool {
+		return result == httpmux.TraceFound || result == httpmux.TraceFoundBeneath
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Routes</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+code { font-family: monospace; }
+.found { color: #060; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Routes</h1>
+<table>
+<tr><th>Host</th><th>Pattern</th><th>Methods</th></tr>
+{{range .Routes}}<tr><td><code>{{.Host}}</code></td><td><code>{{.Pattern}}</code></td><td>{{join .Methods ", "}}{{if .AllMethods}}{{if .Methods}}, {{end}}*{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Explain</h2>
+<form method="get">
+<input name="method" placeholder="GET" value="{{.Query.Get "method"}}" size="8">
+<input name="host" placeholder="host" value="{{.Query.Get "host"}}">
+<input name="path" placeholder="/path" value="{{.Query.Get "path"}}" size="40">
+<button type="submit">Explain</button>
+</form>
+{{with .Explanation}}
+<p><code>{{.Method}} {{.Host}}{{.Path}}</code> responds with <strong>{{.Status}}</strong>{{if .Pattern}} from <code>{{.Pattern}}</code>{{end}}{{if .Redirect}} redirecting to <code>{{.Redirect}}</code>{{end}}{{if .Allow}} allowing {{join .Allow ", "}}{{end}}.</p>
+{{if .Variables}}<ul>
+{{range .Variables}}<li><code>{{.Name}}</code> = <code>{{.Value}}</code></li>
+{{end}}</ul>{{end}}
+<ol>
+{{range .Steps}}<li style="margin-left: {{indent .Depth}}em">{{.Kind}} <code>{{.Value}}</code> at <code>{{.Remaining}}</code>{{if .Captured}} with value <code>{{.Captured}}</code>{{end}}: <span{{if found .Result}} class="found"{{end}}>{{.Result}}</span></li>
+{{end}}</ol>
+{{end}}
+</body>
+</html>
+`))
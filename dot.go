@@ -0,0 +1,115 @@
+package httpmux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//WriteDOT writes the routing tree of m to w in the Graphviz DOT language.
+//
+//Static nodes are boxes labeled with their quoted values, segment variable
+//nodes are ellipses, and end variable nodes are hexagons. Nodes with handlers
+//are drawn bold and also list their methods, with * for a handler registered
+//for all methods. The tree of each host pattern, see Host, is drawn beneath a
+//node for the host pattern.
+//
+//The output only depends on the routes registered, so it may be diffed. Node
+//IDs are derived from the host and pattern of each node, so adding a route does
+//not change the IDs of unrelated nodes.
+func (m *Mux) WriteDOT(w io.Writer) error {
+	_, err := m.routes().load().dot().WriteTo(w)
+	return err
+}
+
+func (tree *routeTree) dot() *bytes.Buffer {
+	d := &dotWriter{buf: &bytes.Buffer{}}
+	d.buf.WriteString("digraph httpmux {\n")
+	d.buf.WriteString("\tnode [fontname=\"monospace\"];\n")
+
+	for _, h := range tree.hosts {
+		host := dotQuote(TraceHost + " " + h.pattern)
+		fmt.Fprintf(d.buf, "\t%s [shape=plaintext, label=%s];\n", host, dotQuote("host "+h.pattern))
+		root := d.writeNode(h.root, h.pattern)
+		fmt.Fprintf(d.buf, "\t%s -> %s;\n", host, root)
+	}
+	d.writeNode(tree.root, "")
+
+	d.buf.WriteString("}\n")
+	return d.buf
+}
+
+type dotWriter struct {
+	buf *bytes.Buffer
+}
+
+//writeNode writes n and its descendants and returns the ID of n. prefix is the
+//host pattern of n's tree followed by the pattern of n's ancestors.
+//
+//IDs are the quoted kind of n and its full pattern, e.g. "static /users", so
+//they do not change when unrelated routes are added.
+func (d *dotWriter) writeNode(n node, prefix string) string {
+	kind, shape, value := TraceStatic, "box", ""
+	children := []node{}
+	switch n := n.(type) {
+	case *staticNode:
+		value = n.value
+		for _, child := range n.staticChildren {
+			children = append(children, child)
+		}
+		if n.segmentVarChild != nil {
+			children = append(children, n.segmentVarChild)
+		}
+		if n.endVarChild != nil {
+			children = append(children, n.endVarChild)
+		}
+
+	case *segmentVarNode:
+		kind, shape, value = TraceSegmentVariable, "ellipse", variablePattern(muxpath.SegmentVarRune, n.name, n.constraint)
+		if n.staticChild != nil {
+			children = append(children, n.staticChild)
+		}
+
+	case *endVarNode:
+		kind, shape, value = TraceEndVariable, "hexagon", variablePattern(muxpath.EndVarRune, n.name, n.constraint)
+	}
+	label := value
+	if kind == TraceStatic {
+		label = strconv.Quote(value)
+		value = escapeStaticPattern(value)
+	}
+	prefix += value
+	id := dotQuote(kind + " " + prefix)
+
+	style := ""
+	if n.isRegistered() {
+		label += "\n" + strings.Join(dotMethods(n.handlers()), ", ")
+		style = ", style=bold"
+	}
+	fmt.Fprintf(d.buf, "\t%s [shape=%s%s, label=%s];\n", id, shape, style, dotQuote(label))
+
+	for _, child := range children {
+		childID := d.writeNode(child, prefix)
+		fmt.Fprintf(d.buf, "\t%s -> %s;\n", id, childID)
+	}
+	return id
+}
+
+func dotMethods(mh *methodHandler) []string {
+	result := mh.listMethods()
+	if mh.all != nil {
+		result = append(result, "*")
+	}
+	return result
+}
+
+//dotQuote returns s as a quoted DOT string with newlines as line breaks.
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + strings.Replace(s, "\n", `\n`, -1) + `"`
+}
@@ -0,0 +1,80 @@
+package httpmux
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMux_WriteDOT(t *testing.T) {
+	m := New()
+	m.Handle("/users", TestHandler("USERS"), "GET", "POST")
+	m.Handle("/users/:id{int}", TestHandler("USER"), "GET")
+	m.Handle("/uploads/*path", TestHandler("UPLOADS"))
+	m.Host("api.example.com").SubRoute("/").Handle(TestHandler("API"), "GET")
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteDOT(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph httpmux {
+	node [fontname="monospace"];
+	"host api.example.com" [shape=plaintext, label="host api.example.com"];
+	"static api.example.com" [shape=box, label="\"\""];
+	"static api.example.com/" [shape=box, style=bold, label="\"/\"\nGET"];
+	"static api.example.com" -> "static api.example.com/";
+	"host api.example.com" -> "static api.example.com";
+	"static " [shape=box, label="\"\""];
+	"static /u" [shape=box, label="\"/u\""];
+	"static /uploads/" [shape=box, label="\"ploads/\""];
+	"end variable /uploads/*path" [shape=hexagon, style=bold, label="*path\n*"];
+	"static /uploads/" -> "end variable /uploads/*path";
+	"static /u" -> "static /uploads/";
+	"static /users" [shape=box, style=bold, label="\"sers\"\nGET, POST"];
+	"static /users/" [shape=box, label="\"/\""];
+	"segment variable /users/:id{int}" [shape=ellipse, style=bold, label=":id{int}\nGET"];
+	"static /users/" -> "segment variable /users/:id{int}";
+	"static /users" -> "static /users/";
+	"static /u" -> "static /users";
+	"static " -> "static /u";
+}
+`
+	if result := buf.String(); result != want {
+		t.Errorf("WriteDOT() =\n%s\nWANT\n%s", result, want)
+	}
+
+	//Nodes keep their IDs when routes that split their ancestors are added.
+	m.Handle("/admin", TestHandler("ADMIN"))
+	buf.Reset()
+	if err := m.WriteDOT(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`	"static /users" [shape=box, style=bold, label="\"sers\"\nGET, POST"];`,
+		`	"static /users/" -> "segment variable /users/:id{int}";`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("WriteDOT() =\n%s\nWANT line %s", buf.String(), line)
+		}
+	}
+}
+
+func TestMux_WriteDOT_escapesStaticValuesInIDs(t *testing.T) {
+	m := New()
+	m.Handle("/a::b/x", TestHandler("STATIC"))
+	m.Handle("/a:b/x", TestHandler("VARIABLE"))
+
+	buf := &bytes.Buffer{}
+	if err := m.WriteDOT(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`	"static /a::b/x" [shape=box, style=bold, label="\":b/x\"\n*"];`,
+		`	"static /a:b/x" [shape=box, style=bold, label="\"/x\"\n*"];`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("WriteDOT() =\n%s\nWANT line %s", buf.String(), line)
+		}
+	}
+}
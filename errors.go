@@ -3,6 +3,8 @@ package httpmux
 import (
 	"fmt"
 	"net/http"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -36,6 +38,8 @@ func serveErrorStatus(w http.ResponseWriter, status int) {
 	http.Error(w, http.StatusText(status), status)
 }
 
+//ErrOverlapStaticVar is no longer returned since static parts and segment
+//variables may overlap. It is kept for compatibility.
 type ErrOverlapStaticVar VarName
 
 func (e ErrOverlapStaticVar) Error() string {
@@ -59,3 +63,163 @@ type ErrUnequalVars struct {
 func (e *ErrUnequalVars) Error() string {
 	return fmt.Sprintf("httpmux: cannot have two unequal variables at the same location %q and %q", e.Variable1, e.Variable2)
 }
+
+type ErrUnknownRouteName string
+
+func (e ErrUnknownRouteName) Error() string {
+	return fmt.Sprintf("httpmux: no route named %q", string(e))
+}
+
+type ErrMissingVariable VarName
+
+func (e ErrMissingVariable) Error() string {
+	return fmt.Sprintf("httpmux: missing value for variable %q", string(e))
+}
+
+type ErrInvalidConstraint struct {
+	Variable   VarName
+	Constraint string
+	Err        error
+}
+
+func (e *ErrInvalidConstraint) Error() string {
+	return fmt.Sprintf("httpmux: invalid constraint %q for variable %q: %v", e.Constraint, e.Variable, e.Err)
+}
+
+type ErrUnequalConstraints struct {
+	Variable    VarName
+	Constraint1 string
+	Constraint2 string
+}
+
+func (e *ErrUnequalConstraints) Error() string {
+	return fmt.Sprintf(
+		"httpmux: cannot have variable %q at the same location with unequal constraints %q and %q",
+		e.Variable,
+		e.Constraint1,
+		e.Constraint2,
+	)
+}
+
+//ErrUnsatisfiedConstraint is the error returned by Route.URL when the Value of
+//a Variable does not satisfy the constraint of its variable.
+type ErrUnsatisfiedConstraint struct {
+	Variable   VarName
+	Constraint string
+	Value      string
+}
+
+func (e *ErrUnsatisfiedConstraint) Error() string {
+	return fmt.Sprintf("httpmux: value %q for variable %q does not satisfy constraint %q", e.Value, e.Variable, e.Constraint)
+}
+
+//ErrNilMatcher is the error that Route.Match panics with when one of its
+//Matchers is nil. It is the index of the nil Matcher.
+type ErrNilMatcher int
+
+func (e ErrNilMatcher) Error() string {
+	return fmt.Sprintf("httpmux: Matcher %d is nil", int(e))
+}
+
+//ErrInvalidRoute is the error returned by TrySubRoute and TryHandle when a
+//pattern cannot be registered. Handle and SubRoute panic with its Err.
+type ErrInvalidRoute struct {
+	//Pattern is the full pattern that could not be registered.
+	Pattern string
+
+	//ConflictingPattern is a previously registered pattern that Pattern
+	//conflicts with. It is empty if Pattern is invalid on its own.
+	ConflictingPattern string
+
+	//Err is the underlying error, e.g. *ErrConsecutiveVars, *ErrUnequalVars,
+	//*ErrUnequalConstraints, or a muxpath.ErrInvalidVariableName.
+	Err error
+}
+
+func (e *ErrInvalidRoute) Error() string {
+	if len(e.ConflictingPattern) == 0 {
+		return fmt.Sprintf("httpmux: invalid route %q: %v", e.Pattern, e.Err)
+	}
+	return fmt.Sprintf("httpmux: invalid route %q conflicts with %q: %v", e.Pattern, e.ConflictingPattern, e.Err)
+}
+
+func (e *ErrInvalidRoute) Unwrap() error {
+	return e.Err
+}
+
+//ErrNotMatched is the error used when a request is found at a Route for its
+//method, but does not satisfy the Matchers of any of the handlers registered
+//there. Its value is the status code to respond with.
+type ErrNotMatched int
+
+func (e ErrNotMatched) Error() string {
+	return http.StatusText(int(e))
+}
+
+func (e ErrNotMatched) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serveErrorStatus(w, int(e))
+}
+
+//ErrInvalidVariable is the error returned when the Value of a Variable cannot
+//be converted to the requested type.
+type ErrInvalidVariable struct {
+	Variable VarName
+	Value    string
+	Err      error
+}
+
+func newErrInvalidVariable(name, value string, err error) *ErrInvalidVariable {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		err = numErr.Err
+	}
+	return &ErrInvalidVariable{Variable: VarName(name), Value: value, Err: err}
+}
+
+func (e *ErrInvalidVariable) Error() string {
+	return fmt.Sprintf("httpmux: invalid value %q for variable %q: %v", e.Value, e.Variable, e.Err)
+}
+
+func (e *ErrInvalidVariable) Unwrap() error {
+	return e.Err
+}
+
+//ErrBindVariables is the error returned by BindVariables with an
+//*ErrInvalidVariable for every field that could not be set.
+type ErrBindVariables []*ErrInvalidVariable
+
+func (e ErrBindVariables) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = fmt.Sprintf("%q: %v", err.Variable, err.Err)
+	}
+	return "httpmux: cannot bind variables " + strings.Join(messages, ", ")
+}
+
+func (e ErrBindVariables) Unwrap() []error {
+	result := make([]error, len(e))
+	for i, err := range e {
+		result[i] = err
+	}
+	return result
+}
+
+//ErrInvalidBindTarget is the error returned by BindVariables when its
+//destination is not a non-nil pointer to a struct.
+type ErrInvalidBindTarget struct {
+	Type reflect.Type
+}
+
+func (e *ErrInvalidBindTarget) Error() string {
+	return fmt.Sprintf("httpmux: cannot bind variables to %v", e.Type)
+}
+
+//ErrInvalidBindTag is the error returned by BindVariables when the BindTag tag
+//of a field cannot be parsed.
+type ErrInvalidBindTag struct {
+	Field string
+	Tag   string
+}
+
+func (e *ErrInvalidBindTag) Error() string {
+	return fmt.Sprintf("httpmux: invalid %v tag %q on field %v", BindTag, e.Tag, e.Field)
+}
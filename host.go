@@ -0,0 +1,114 @@
+package httpmux
+
+import "strings"
+
+const (
+	HostVarStartRune = '{'
+	HostVarEndRune   = '}'
+
+	hostSeparator = "."
+)
+
+//Host returns the root Route of the routes that are only served for requests
+//whose host matches pattern. Calling Host again with the same pattern returns
+//the same Route.
+//
+//pattern is a host name, without a port, whose labels may be variables such
+//as "{tenant}.example.com". A variable matches exactly one label and its value
+//is available from VariablesFrom before the variables of the request path.
+//Static labels match case-insensitively.
+//
+//Host patterns are tried in the order they were added. If no host pattern
+//matches the request, or the request path is not found beneath any matching
+//host pattern, then the routes registered without a host are searched.
+func (m *Mux) Host(pattern string) *Route {
+	return m.routes().host(pattern)
+}
+
+//hostRoute is a tree of routes served only for hosts that match pattern.
+type hostRoute struct {
+	pattern string
+	labels  []string
+
+	root *staticNode
+}
+
+func newHostRoute(pattern string) *hostRoute {
+	return &hostRoute{
+		pattern: pattern,
+		labels:  strings.Split(pattern, hostSeparator),
+		root:    &staticNode{},
+	}
+}
+
+func (t *routeTable) host(pattern string) *Route {
+	if !t.load().hasHost(pattern) {
+		t.update(func(tree *routeTree) error {
+			tree.rootOf(pattern)
+			return nil
+		})
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	root, ok := t.hostRoots[pattern]
+	if !ok {
+		root = newRoute(pattern, "", t)
+		t.hostRoots[pattern] = root
+	}
+	return root
+}
+
+func (tree *routeTree) hasHost(pattern string) bool {
+	for _, h := range tree.hosts {
+		if h.pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+//matches returns whether host matches h's pattern and adds the values of h's
+//variables to mt if it does.
+func (h *hostRoute) matches(host string, mt *match) bool {
+	host = stripPort(host)
+	if strings.Count(host, hostSeparator) != len(h.labels)-1 {
+		return false
+	}
+
+	vars := mt.vars
+	for _, label := range h.labels {
+		index := strings.Index(host, hostSeparator)
+		if index < 0 {
+			index = len(host)
+		}
+		value := host[:index]
+		if name, ok := extractHostVarName(label); ok {
+			vars = append(vars, Variable{Name: VarName(name), Value: value, RawValue: value})
+		} else if !strings.EqualFold(label, value) {
+			return false
+		}
+		if index < len(host) {
+			index++
+		}
+		host = host[index:]
+	}
+	mt.vars = vars
+	return true
+}
+
+func extractHostVarName(label string) (string, bool) {
+	if len(label) < 2 || label[0] != HostVarStartRune || label[len(label)-1] != HostVarEndRune {
+		return "", false
+	}
+	return label[1 : len(label)-1], true
+}
+
+func stripPort(host string) string {
+	index := strings.LastIndex(host, ":")
+	if index < 0 || strings.Contains(host[index:], "]") {
+		return host
+	}
+	return host[:index]
+}
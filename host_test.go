@@ -0,0 +1,155 @@
+package httpmux
+
+import "testing"
+
+func TestMux_Host_ServesHostRoutes(t *testing.T) {
+	m := New()
+
+	m.Host("{tenant}.example.com").SubRoute("/users/:id").Handle(TestHandler("TENANT_USER"))
+	m.Host("api.{region}.example.com").SubRoute("/").Handle(TestHandler("API_ROOT"))
+	m.Host("www.example.com").SubRoute("/about").Handle(TestHandler("WWW_ABOUT"))
+	m.Handle("/about", TestHandler("ABOUT"))
+	m.Handle("/users/:id", TestHandler("USER"))
+
+	if m.Host("www.example.com") != m.Host("www.example.com") {
+		t.Error("m.Host() must return the same Route for the same pattern")
+	}
+
+	tests := []*ServeHTTPTest{
+		{
+			Method: "GET",
+			Path:   "http://acme.example.com/users/1",
+			Status: 200,
+			Body:   "TENANT_USER",
+			Variables: []*Variable{
+				{Name: "tenant", Value: "acme", RawValue: "acme"},
+				{Name: "id", Value: "1", RawValue: "1"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "http://Acme.EXAMPLE.com:8080/users/2",
+			Status: 200,
+			Body:   "TENANT_USER",
+			Variables: []*Variable{
+				{Name: "tenant", Value: "Acme", RawValue: "Acme"},
+				{Name: "id", Value: "2", RawValue: "2"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "http://api.eu.example.com",
+			Status: 200,
+			Body:   "API_ROOT",
+			Variables: []*Variable{
+				{Name: "region", Value: "eu", RawValue: "eu"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "http://www.example.com/about",
+			Status: 200,
+			Body:   "WWW_ABOUT",
+		},
+		{
+			Method: "GET",
+			Path:   "http://acme.example.com/about",
+			Status: 200,
+			Body:   "ABOUT",
+		},
+		{
+			Method: "GET",
+			Path:   "http://other.com/users/3",
+			Status: 200,
+			Body:   "USER",
+			Variables: []*Variable{
+				{Name: "id", Value: "3", RawValue: "3"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "http://a.b.example.com/none",
+			Status: 404,
+			Body:   NotFoundBody,
+		},
+	}
+
+	testMux_ServeHTTP(t, m, tests...)
+
+	hosts := []string{}
+	m.Walk(func(info RouteInfo) error {
+		hosts = append(hosts, info.Host+info.Pattern)
+		return nil
+	})
+	want := []string{
+		"{tenant}.example.com/users/:id",
+		"api.{region}.example.com/",
+		"www.example.com/about",
+		"/about",
+		"/users/:id",
+	}
+	if len(hosts) != len(want) {
+		t.Fatalf("m.Walk() hosts = %v WANT %v", hosts, want)
+	}
+	for i := range want {
+		if hosts[i] != want[i] {
+			t.Errorf("m.Walk() hosts = %v WANT %v", hosts, want)
+		}
+	}
+}
+
+func TestRoute_Remove_RemovesHostRoutes(t *testing.T) {
+	m := New()
+
+	tenant := m.Host("{tenant}.example.com")
+	tenant.SubRoute("/users/:id").Handle(TestHandler("TENANT_USER"))
+	m.Handle("/users/:id", TestHandler("USER"))
+
+	if !tenant.Remove("/users/:id") {
+		t.Fatal("tenant.Remove() = false WANT true")
+	}
+	if tenant.Remove("/users/:id") {
+		t.Error("tenant.Remove() = true WANT false once removed")
+	}
+	if m.Host("other.example.com").Remove("/users/:id") {
+		t.Error("Remove() = true WANT false for a host without the route")
+	}
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{
+			Method:    "GET",
+			Path:      "http://acme.example.com/users/1",
+			Status:    200,
+			Body:      "USER",
+			Variables: []*Variable{{Name: "id", Value: "1", RawValue: "1"}},
+		},
+	)
+}
+
+func TestMux_URL_includesHostPatterns(t *testing.T) {
+	m := New()
+
+	m.Host("{tenant}.example.com").SubRoute("/users/:id").Name("user")
+	m.Host("www.example.com").SubRoute("/about").Name("about")
+
+	tests := []struct {
+		name string
+		vars []*Variable
+
+		result string
+		err    error
+	}{
+		{"user", []*Variable{{Name: "tenant", Value: "acme"}, {Name: "id", Value: "1"}}, "//acme.example.com/users/1", nil},
+		{"user", []*Variable{{Name: "id", Value: "1"}}, "", ErrMissingVariable("tenant")},
+		{"about", nil, "//www.example.com/about", nil},
+	}
+
+	for i, test := range tests {
+		result, err := m.URL(test.name, test.vars...)
+		if result != test.result || err != test.err {
+			t.Errorf("%v: m.URL(%q) = %q, %v WANT %q, %v", i, test.name, result, err, test.result, test.err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//Package yaml parses and writes the small subset of YAML used by the httpmux
+//subpackages.
+//
+//Parse supports:
+//
+//	- block mappings and block lists, indented with spaces
+//	- plain, single quoted, and double quoted scalars, including as mapping keys
+//	- the null scalars ~ and null
+//	- flow lists of scalars, e.g. [a, 'b, c'], and the empty flow mapping {}
+//	- comments and the --- document start marker
+//
+//Everything else is rejected with a *SyntaxError, including tabs in
+//indentation, flow mappings other than {}, flow list items that are not
+//scalars, anchors (&), aliases (*), tags (!), block scalars (| and >),
+//directives (%), duplicate mapping keys, and plain scalars starting with @ or
+//`. Scalars are never converted into numbers or booleans.
+//
+//Marshal writes block mappings with sorted keys, block lists, empty flow
+//mappings and lists, and scalars. Strings are always double quoted, so they
+//are never mistaken for other scalars, and the output can be read by Parse.
+package yaml
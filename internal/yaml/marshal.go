@@ -0,0 +1,92 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Marshal returns value as YAML. value must be a decoded JSON value as
+//returned by json.Unmarshal into an interface{}: a map[string]interface{}, an
+//[]interface{}, a string, a float64, a bool, or nil.
+func Marshal(value interface{}) []byte {
+	buf := &bytes.Buffer{}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		writeBlock(buf, value, 0)
+	default:
+		buf.WriteString(scalar(value) + "\n")
+	}
+	return buf.Bytes()
+}
+
+//writeBlock writes value, a mapping or list, as the block contents of its
+//parent at indent.
+func writeBlock(buf *bytes.Buffer, value interface{}, indent int) {
+	prefix := strings.Repeat("  ", indent)
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			buf.WriteString(prefix + quote(key) + ":")
+			writeChild(buf, value[key], indent+1)
+		}
+
+	case []interface{}:
+		for _, item := range value {
+			buf.WriteString(prefix + "-")
+			writeChild(buf, item, indent+1)
+		}
+	}
+}
+
+//writeChild writes value after a key or list item marker that has just been
+//written.
+func writeChild(buf *bytes.Buffer, value interface{}, indent int) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if len(typed) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeBlock(buf, typed, indent)
+
+	case []interface{}:
+		if len(typed) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeBlock(buf, typed, indent)
+
+	default:
+		buf.WriteString(" " + scalar(value) + "\n")
+	}
+}
+
+func scalar(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return quote(value)
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return "null"
+}
+
+//quote returns s as a double quoted YAML string. JSON strings are valid YAML
+//double quoted strings.
+func quote(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
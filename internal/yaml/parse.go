@@ -0,0 +1,385 @@
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Kind is the kind of a Node.
+type Kind int
+
+const (
+	Null Kind = iota
+	Scalar
+	List
+	Map
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Scalar:
+		return "a scalar"
+	case List:
+		return "a list"
+	case Map:
+		return "a mapping"
+	}
+	return "null"
+}
+
+//Node is a parsed YAML value along with the line it starts on.
+type Node struct {
+	Line int
+	Kind Kind
+
+	//Scalar is the unquoted value of a Scalar.
+	Scalar string
+
+	List []*Node
+
+	//Keys are the keys of Fields in the order they appear.
+	Keys   []string
+	Fields map[string]*Node
+}
+
+func newMapNode(line int) *Node {
+	return &Node{Line: line, Kind: Map, Fields: map[string]*Node{}}
+}
+
+//set adds the field key with value to n and returns an error if key is
+//already in n.
+func (n *Node) set(line int, key string, value *Node) error {
+	if _, ok := n.Fields[key]; ok {
+		return &SyntaxError{Line: line, Err: fmt.Errorf("duplicate key %q", key)}
+	}
+	n.Keys = append(n.Keys, key)
+	n.Fields[key] = value
+	return nil
+}
+
+//SyntaxError is an error at a line of YAML that is invalid or outside of the
+//supported subset.
+type SyntaxError struct {
+	Line int
+	Err  error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+//sourceLine is a line of YAML that has content after its comment is removed.
+type sourceLine struct {
+	number  int
+	indent  int
+	content string
+}
+
+//parser parses the subset of YAML described in the package documentation.
+type parser struct {
+	lines []*sourceLine
+	i     int
+}
+
+//Parse parses data into a Node. It returns a *SyntaxError if data is not in
+//the supported subset of YAML. An empty document is a Null Node.
+func Parse(data []byte) (*Node, error) {
+	lines, err := splitLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &Node{Line: 1, Kind: Null}, nil
+	}
+
+	p := &parser{lines: lines}
+	result, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.lines) {
+		return nil, p.errorf(p.lines[p.i], "unexpected indentation")
+	}
+	return result, nil
+}
+
+func splitLines(data string) ([]*sourceLine, error) {
+	result := []*sourceLine{}
+	for i, raw := range strings.Split(data, "\n") {
+		content := removeComment(strings.TrimRight(raw, "\r"))
+		trimmed := strings.TrimLeft(content, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, &SyntaxError{Line: i + 1, Err: errors.New("tabs are not allowed in indentation")}
+		}
+		trimmed = strings.TrimRight(trimmed, " \t")
+		if len(trimmed) == 0 || trimmed == "---" {
+			continue
+		}
+		result = append(result, &sourceLine{
+			number:  i + 1,
+			indent:  len(content) - len(strings.TrimLeft(content, " ")),
+			content: trimmed,
+		})
+	}
+	return result, nil
+}
+
+//removeComment returns line without a comment that starts with a # that is
+//not inside of a quoted scalar.
+func removeComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case (c == '"' || c == '\'') && startsScalar(line, i):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+//startsScalar returns whether a scalar may start at index i of line.
+func startsScalar(line string, i int) bool {
+	return len(strings.TrimSpace(line[:i])) == 0 || strings.IndexByte(" \t[,", line[i-1]) >= 0
+}
+
+func (p *parser) errorf(line *sourceLine, format string, args ...interface{}) error {
+	return &SyntaxError{Line: line.number, Err: fmt.Errorf(format, args...)}
+}
+
+//parseBlock parses the list or mapping whose lines start at indent.
+func (p *parser) parseBlock(indent int) (*Node, error) {
+	if isListItem(p.lines[p.i].content) {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *parser) parseList(indent int) (*Node, error) {
+	result := &Node{Line: p.lines[p.i].number, Kind: List}
+
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent && isListItem(p.lines[p.i].content) {
+		line := p.lines[p.i]
+		rest := strings.TrimLeft(line.content[1:], " ")
+
+		var item *Node
+		var err error
+		switch {
+		case len(rest) == 0:
+			p.i++
+			item, err = p.parseNested(line, indent, false)
+
+		case isListItem(rest) || isMapEntry(rest):
+			//The item is a block that starts on this line, so the line is
+			//treated as if it only had the item, indented by its position.
+			line.indent += len(line.content) - len(rest)
+			line.content = rest
+			item, err = p.parseBlock(line.indent)
+
+		default:
+			p.i++
+			item, err = parseFlow(rest, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.List = append(result.List, item)
+	}
+	return result, nil
+}
+
+func (p *parser) parseMap(indent int) (*Node, error) {
+	result := newMapNode(p.lines[p.i].number)
+
+	for p.i < len(p.lines) && p.lines[p.i].indent == indent {
+		line := p.lines[p.i]
+		key, rest, ok := splitMapEntry(line.content)
+		if !ok {
+			return nil, p.errorf(line, "expected a mapping key")
+		}
+		p.i++
+
+		var item *Node
+		var err error
+		if len(rest) == 0 {
+			item, err = p.parseNested(line, indent, true)
+		} else {
+			item, err = parseFlow(rest, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := result.set(line.number, key, item); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+//parseNested parses the block after parent, which has no inline value, or
+//returns null if there is none. A list at the same indentation as parent is
+//the value of parent if parent is a mapping key.
+func (p *parser) parseNested(parent *sourceLine, indent int, isKey bool) (*Node, error) {
+	if p.i < len(p.lines) {
+		next := p.lines[p.i]
+		if next.indent > indent || (isKey && next.indent == indent && isListItem(next.content)) {
+			return p.parseBlock(next.indent)
+		}
+	}
+	return &Node{Line: parent.number, Kind: Null}, nil
+}
+
+func isListItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func isMapEntry(content string) bool {
+	_, _, ok := splitMapEntry(content)
+	return ok
+}
+
+//splitMapEntry splits content into a mapping key and the rest of content
+//after the key's colon.
+func splitMapEntry(content string) (key, rest string, ok bool) {
+	if len(content) == 0 || strings.IndexByte("[{", content[0]) >= 0 {
+		return "", "", false
+	}
+
+	if content[0] == '"' || content[0] == '\'' {
+		end := indexOfQuoteEnd(content)
+		if end < 0 || !strings.HasPrefix(content[end+1:], ":") {
+			return "", "", false
+		}
+		unquoted, err := parseScalar(content[:end+1], 0)
+		if err != nil {
+			return "", "", false
+		}
+		rest = content[end+2:]
+		if len(rest) > 0 && rest[0] != ' ' {
+			return "", "", false
+		}
+		return unquoted.Scalar, strings.TrimSpace(rest), true
+	}
+
+	index := strings.Index(content, ": ")
+	if index < 0 {
+		if !strings.HasSuffix(content, ":") {
+			return "", "", false
+		}
+		index = len(content) - 1
+	}
+	return strings.TrimSpace(content[:index]), strings.TrimSpace(content[index+1:]), true
+}
+
+//indexOfQuoteEnd returns the index of the quote that ends the quoted
+//scalar at the start of s, or -1 if there is none.
+func indexOfQuoteEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+//parseFlow parses an inline value, which is a scalar or a flow list of
+//scalars.
+func parseFlow(s string, line int) (*Node, error) {
+	switch s[0] {
+	case '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, &SyntaxError{Line: line, Err: errors.New("unterminated flow list")}
+		}
+		return parseFlowList(s[1:len(s)-1], line)
+
+	case '{':
+		if strings.TrimSpace(s[1:]) == "}" {
+			return newMapNode(line), nil
+		}
+		return nil, &SyntaxError{Line: line, Err: errors.New("flow mappings are not supported")}
+
+	case '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, &SyntaxError{Line: line, Err: fmt.Errorf("unsupported YAML syntax %q", s[0])}
+	}
+	return parseScalar(s, line)
+}
+
+func parseFlowList(s string, line int) (*Node, error) {
+	result := &Node{Line: line, Kind: List}
+	if len(strings.TrimSpace(s)) == 0 {
+		return result, nil
+	}
+
+	for len(s) > 0 {
+		s = strings.TrimSpace(s)
+		end := strings.IndexByte(s, ',')
+		if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+			quoteEnd := indexOfQuoteEnd(s)
+			if quoteEnd < 0 {
+				return nil, &SyntaxError{Line: line, Err: errors.New("unterminated quoted scalar")}
+			}
+			end = strings.IndexByte(s[quoteEnd:], ',')
+			if end >= 0 {
+				end += quoteEnd
+			}
+		}
+		item := s
+		if end >= 0 {
+			item, s = s[:end], s[end+1:]
+		} else {
+			s = ""
+		}
+
+		item = strings.TrimSpace(item)
+		if len(item) == 0 || strings.IndexByte("[{", item[0]) >= 0 {
+			return nil, &SyntaxError{Line: line, Err: errors.New("flow list items must be scalars")}
+		}
+		parsed, err := parseScalar(item, line)
+		if err != nil {
+			return nil, err
+		}
+		result.List = append(result.List, parsed)
+	}
+	return result, nil
+}
+
+func parseScalar(s string, line int) (*Node, error) {
+	switch {
+	case s[0] == '"':
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return nil, &SyntaxError{Line: line, Err: fmt.Errorf("invalid double quoted scalar %s", s)}
+		}
+		return &Node{Line: line, Kind: Scalar, Scalar: unquoted}, nil
+
+	case s[0] == '\'':
+		if len(s) < 2 || indexOfQuoteEnd(s) != len(s)-1 {
+			return nil, &SyntaxError{Line: line, Err: fmt.Errorf("invalid single quoted scalar %s", s)}
+		}
+		return &Node{Line: line, Kind: Scalar, Scalar: strings.Replace(s[1:len(s)-1], "''", "'", -1)}, nil
+
+	case s == "~" || s == "null":
+		return &Node{Line: line, Kind: Null}, nil
+	}
+	return &Node{Line: line, Kind: Scalar, Scalar: s}, nil
+}
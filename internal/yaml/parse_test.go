@@ -0,0 +1,158 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//plain converts n into plain Go values for comparison.
+func plain(n *Node) interface{} {
+	switch n.Kind {
+	case Scalar:
+		return n.Scalar
+	case List:
+		result := []interface{}{}
+		for _, item := range n.List {
+			result = append(result, plain(item))
+		}
+		return result
+	case Map:
+		result := map[string]interface{}{}
+		for key, field := range n.Fields {
+			result[key] = plain(field)
+		}
+		return result
+	}
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data   string
+		result interface{}
+	}{
+		{"", nil},
+		{"---\na: b\n", map[string]interface{}{"a": "b"}},
+		{
+			"a: /users/:id # comment\nb: \"x # y\"\nc: 'it''s'\nd: ~\ne: []\nf: {}\n",
+			map[string]interface{}{
+				"a": "/users/:id", "b": "x # y", "c": "it's", "d": nil, "e": []interface{}{}, "f": map[string]interface{}{},
+			},
+		},
+		{
+			"a:\n- b\n- c\nd:\n  e: [f, 'g, h', \"i\"]\n",
+			map[string]interface{}{
+				"a": []interface{}{"b", "c"},
+				"d": map[string]interface{}{"e": []interface{}{"f", "g, h", "i"}},
+			},
+		},
+		{
+			"- a: b\n  c: d\n-\n  - e\n- - f\n  - g\n- \"h: i\"\n",
+			[]interface{}{
+				map[string]interface{}{"a": "b", "c": "d"},
+				[]interface{}{"e"},
+				[]interface{}{"f", "g"},
+				"h: i",
+			},
+		},
+		{"\"a: b\": c\n'd': e\n", map[string]interface{}{"a: b": "c", "d": "e"}},
+		{"a: b:c\n", map[string]interface{}{"a": "b:c"}},
+		{"a: 1\nb: true\n", map[string]interface{}{"a": "1", "b": "true"}},
+	}
+
+	for i, test := range tests {
+		n, err := Parse([]byte(test.data))
+		if err != nil {
+			t.Errorf("%d: Parse() error = %v", i, err)
+			continue
+		}
+		if result := plain(n); !reflect.DeepEqual(result, test.result) {
+			t.Errorf("%d: Parse() = %#v WANT %#v", i, result, test.result)
+		}
+	}
+}
+
+func TestParse_keepsTheOrderOfKeys(t *testing.T) {
+	n, err := Parse([]byte("b: 1\na: 2\nc: 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(n.Keys, want) {
+		t.Errorf("Keys = %v WANT %v", n.Keys, want)
+	}
+}
+
+func TestParse_rejectsUnsupportedSyntax(t *testing.T) {
+	tests := []struct {
+		data string
+		line int
+	}{
+		{"a: b\n  c: d\n", 2},
+		{"a: b\nc\n", 2},
+		{"a: [b\n", 1},
+		{"a: \"b\n", 1},
+		{"a: 'b\n", 1},
+		{"a: b\na: c\n", 2},
+		{"a:\n\t- b\n", 2},
+		{"a: {b: c}\n", 1},
+		{"a: [b, [c]]\n", 1},
+		{"a: [b, {}]\n", 1},
+		{"a: &b c\n", 1},
+		{"a: *b\n", 1},
+		{"a: !tag b\n", 1},
+		{"a: |\n  b\n", 1},
+		{"a: >\n  b\n", 1},
+		{"a: %b\n", 1},
+		{"a: @b\n", 1},
+		{"a: `b`\n", 1},
+		{"a: \"\\q\"\n", 1},
+	}
+
+	for i, test := range tests {
+		_, err := Parse([]byte(test.data))
+		syntaxErr, ok := err.(*SyntaxError)
+		if !ok || syntaxErr.Line != test.line {
+			t.Errorf("%d: Parse(%q) error = %v WANT line %d", i, test.data, err, test.line)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	value := map[string]interface{}{
+		"b": []interface{}{"x", 1.5, true, nil, map[string]interface{}{}, []interface{}{}},
+		"a": map[string]interface{}{"c": "it's: #1", "d": []interface{}{map[string]interface{}{"e": "f"}}},
+	}
+	want := strings.Join([]string{
+		`"a":`,
+		`  "c": "it's: #1"`,
+		`  "d":`,
+		`    -`,
+		`      "e": "f"`,
+		`"b":`,
+		`  - "x"`,
+		`  - 1.5`,
+		`  - true`,
+		`  - null`,
+		`  - {}`,
+		`  - []`,
+		``,
+	}, "\n")
+
+	result := Marshal(value)
+	if string(result) != want {
+		t.Fatalf("Marshal() = %s WANT %s", result, want)
+	}
+
+	n, err := Parse(result)
+	if err != nil {
+		t.Fatalf("Parse(Marshal()) error = %v", err)
+	}
+	parsed := plain(n).(map[string]interface{})
+	if c := parsed["a"].(map[string]interface{})["c"]; c != "it's: #1" {
+		t.Errorf("Parse(Marshal()) a.c = %#v WANT %#v", c, "it's: #1")
+	}
+	if b := parsed["b"]; !reflect.DeepEqual(b, []interface{}{"x", "1.5", "true", nil, map[string]interface{}{}, []interface{}{}}) {
+		t.Errorf("Parse(Marshal()) b = %#v", b)
+	}
+}
@@ -0,0 +1,188 @@
+package httpmux
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const defaultMatchCapacity = 8
+
+var matchPool = sync.Pool{
+	New: func() interface{} {
+		return &match{
+			vars: make([]Variable, 0, defaultMatchCapacity),
+		}
+	},
+}
+
+//match accumulates the variables of the nodes along the path to a found node,
+//and holds the middleware of the found node.
+//
+//matches are pooled. A match obtained with newMatch is reused once release is
+//called and must not be referenced afterwards.
+type match struct {
+	vars       []Variable
+	middleware []Middleware
+
+	//escaped is true if the path being searched is escaped, in which case
+	//captured values are unescaped.
+	escaped bool
+
+	//ignoreCase is true if static parts of the path may match ignoring case.
+	//folds holds the static parts that only matched ignoring case.
+	ignoreCase bool
+	folds      []caseFold
+
+	//host, pattern, and meta describe the Route that the path was found at.
+	host    string
+	pattern string
+	meta    map[string]interface{}
+
+	//trace records the steps of the search if it is not nil. Pooled matches
+	//never have one.
+	trace *tracer
+}
+
+//caseFold is a static value that matched the path ending with remaining bytes
+//only when ignoring case.
+type caseFold struct {
+	remaining int
+	value     string
+}
+
+func newMatch() *match {
+	return matchPool.Get().(*match)
+}
+
+func (mt *match) release() {
+	mt.clear()
+	mt.escaped = false
+	mt.ignoreCase = false
+	matchPool.Put(mt)
+}
+
+//clear removes everything that a search added to mt.
+func (mt *match) clear() {
+	mt.reset(matchMark{})
+	mt.host, mt.pattern, mt.meta, mt.middleware = "", "", nil, nil
+}
+
+type matchMark struct {
+	vars  int
+	folds int
+}
+
+//mark returns a mark that mt can be reset to if the search through a node
+//fails.
+func (mt *match) mark() matchMark {
+	return matchMark{len(mt.vars), len(mt.folds)}
+}
+
+func (mt *match) reset(mark matchMark) {
+	for i := mark.vars; i < len(mt.vars); i++ {
+		mt.vars[i] = Variable{}
+	}
+	mt.vars = mt.vars[:mark.vars]
+	mt.folds = mt.folds[:mark.folds]
+}
+
+//fold records that value matched the beginning of path only when ignoring
+//case.
+func (mt *match) fold(path, value string) {
+	mt.folds = append(mt.folds, caseFold{remaining: len(path), value: value})
+}
+
+//canonicalPath returns path, the path that was searched, with the static parts
+//that only matched ignoring case replaced by their registered values.
+func (mt *match) canonicalPath(path string) string {
+	if len(mt.folds) == 0 {
+		return path
+	}
+	result := []byte(path)
+	for _, f := range mt.folds {
+		copy(result[len(result)-f.remaining:], f.value)
+	}
+	return string(result)
+}
+
+//capture adds a Variable named name with the value found in the path if it
+//satisfies c, and returns whether it did. end is true if the variable is an
+//end variable, whose value may contain slashes.
+func (mt *match) capture(name VarName, found string, c *constraint, end bool) bool {
+	v := Variable{Name: name, Value: found, RawValue: found, Constraint: c.String()}
+	switch {
+	case mt.escaped:
+		if unescaped, err := url.PathUnescape(found); err == nil {
+			v.Value = unescaped
+		}
+	case end:
+		v.RawValue = escapeEndValue(found)
+	default:
+		v.RawValue = url.PathEscape(found)
+	}
+	if !c.match(v.Value) {
+		return false
+	}
+	mt.vars = append(mt.vars, v)
+	return true
+}
+
+//wrap wraps handler in mt's middleware so that the first middleware is the
+//outermost.
+func (mt *match) wrap(handler http.Handler) http.Handler {
+	return wrapMiddleware(handler, mt.middleware)
+}
+
+//variablesContext is the Context of requests found at a Route. It holds copies
+//of what a match found, so it stays valid after the match is released.
+type variablesContext struct {
+	context.Context
+	vars  []Variable
+	route MatchedRoute
+
+	//buf holds vars if there are few enough of them, so that creating a
+	//variablesContext usually allocates once.
+	buf [4]Variable
+}
+
+func newVariablesContext(parent context.Context, mt *match) *variablesContext {
+	c := &variablesContext{
+		Context: parent,
+		route:   MatchedRoute{Host: mt.host, Pattern: mt.pattern, Meta: mt.meta},
+	}
+	c.vars = append(c.buf[:0:len(c.buf)], mt.vars...)
+	return c
+}
+
+//Value returns the Value of the first Variable named key if key is a VarName.
+//VarName keys are only supported for compatibility, see VariableFrom.
+func (c *variablesContext) Value(key interface{}) interface{} {
+	switch key := key.(type) {
+	case variablesKey:
+		if key == variablesKeyValue {
+			return c
+		}
+	case VarName:
+		if v, ok := c.variable(key); ok {
+			return v.Value
+		}
+	}
+	return c.Context.Value(key)
+}
+
+//variable returns the first Variable in c named name.
+func (c *variablesContext) variable(name VarName) (Variable, bool) {
+	for _, v := range c.vars {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variable{}, false
+}
+
+func variablesFrom(c context.Context) *variablesContext {
+	vc, _ := c.Value(variablesKeyValue).(*variablesContext)
+	return vc
+}
@@ -0,0 +1,302 @@
+package httpmux
+
+import (
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//Matcher is a predicate on requests that distinguishes between handlers
+//registered at the same Route for the same method. See Route.Match.
+type Matcher interface {
+	//Match returns whether r satisfies the Matcher.
+	Match(r *http.Request) bool
+
+	//MismatchStatus returns the status code used when a request does not
+	//satisfy the Matcher.
+	MismatchStatus() int
+}
+
+type matcherFunc struct {
+	status int
+	match  func(r *http.Request) bool
+}
+
+//MatcherFunc returns a Matcher that uses match as its Match method and status
+//as its MismatchStatus.
+func MatcherFunc(status int, match func(r *http.Request) bool) Matcher {
+	return &matcherFunc{status: status, match: match}
+}
+
+func (m *matcherFunc) Match(r *http.Request) bool {
+	return m.match(r)
+}
+
+func (m *matcherFunc) MismatchStatus() int {
+	return m.status
+}
+
+//Accept returns a Matcher that matches requests whose Accept header accepts
+//any of mediaTypes. Requests without an Accept header accept every media type.
+//Its MismatchStatus is Not Acceptable.
+func Accept(mediaTypes ...string) Matcher {
+	return MatcherFunc(http.StatusNotAcceptable, func(r *http.Request) bool {
+		accept := r.Header.Get("Accept")
+		if len(accept) == 0 {
+			return true
+		}
+		for _, mediaType := range mediaTypes {
+			if acceptsMediaType(accept, mediaType) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+//acceptsMediaType returns whether the media ranges in the Accept header value
+//accept include mediaType.
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok && strings.Trim(q, "0.") == "" {
+			continue
+		}
+		if mediaRangeMatches(rangeType, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || strings.EqualFold(mediaRange, mediaType) {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(strings.ToLower(mediaType), mediaRange[:len(mediaRange)-1])
+	}
+	return false
+}
+
+//ContentType returns a Matcher that matches requests whose Content-Type header
+//is any of mediaTypes, ignoring parameters.
+//Its MismatchStatus is Unsupported Media Type.
+func ContentType(mediaTypes ...string) Matcher {
+	return MatcherFunc(http.StatusUnsupportedMediaType, func(r *http.Request) bool {
+		contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+		for _, mediaType := range mediaTypes {
+			if strings.EqualFold(contentType, mediaType) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+//Header returns a Matcher that matches requests with a header named name whose
+//value is value, or with any value if value is empty.
+//Its MismatchStatus is Bad Request.
+func Header(name, value string) Matcher {
+	return MatcherFunc(http.StatusBadRequest, func(r *http.Request) bool {
+		values, ok := r.Header[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return false
+		}
+		return len(value) == 0 || containsString(values, value)
+	})
+}
+
+//Query returns a Matcher that matches requests with a query parameter named
+//name whose value is value, or with any value if value is empty.
+//Its MismatchStatus is Bad Request.
+func Query(name, value string) Matcher {
+	return MatcherFunc(http.StatusBadRequest, func(r *http.Request) bool {
+		values, ok := r.URL.Query()[name]
+		if !ok {
+			return false
+		}
+		return len(value) == 0 || containsString(values, value)
+	})
+}
+
+//Scheme returns a Matcher that matches requests made with any of schemes, e.g.
+//"https". The scheme of a request is taken from its URL if present, and is
+//otherwise "https" for TLS requests and "http" for the rest.
+//Its MismatchStatus is Bad Request.
+func Scheme(schemes ...string) Matcher {
+	return MatcherFunc(http.StatusBadRequest, func(r *http.Request) bool {
+		scheme := r.URL.Scheme
+		if len(scheme) == 0 {
+			scheme = "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+		}
+		for _, s := range schemes {
+			if strings.EqualFold(s, scheme) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Match returns a Route at the same location as r whose handlers are only
+//served for requests that satisfy all of matchers, and all of the Matchers r
+//already has. The Matchers are not inherited by sub routes.
+//
+//Handlers registered with Matchers for the same method are tried in order of
+//descending number of Matchers, and then in the order they were registered. A
+//handler registered without Matchers is served if no other handler matches.
+//If no handler matches, then an ErrNotMatched with the MismatchStatus of the
+//first Matcher that failed for the first handler tried is served.
+//
+//Registering a handler with the same Matchers, compared with ==, for the same
+//method replaces the handler registered before, and registering a nil handler
+//removes it. The returned Route can be kept to do so:
+//
+//	json := r.Match(Accept("application/json"))
+//	json.Get(handler)
+//	json.Handle(nil, "GET")
+//
+//Match panics with an ErrNilMatcher if any of matchers is nil.
+func (r *Route) Match(matchers ...Matcher) *Route {
+	for i, matcher := range matchers {
+		if matcher == nil {
+			panic(ErrNilMatcher(i))
+		}
+	}
+	result := *r
+	result.matchers = append(append([]Matcher{}, r.matchers...), matchers...)
+	return &result
+}
+
+//matchingHandler chooses between handlers registered with Matchers.
+type matchingHandler struct {
+	alternatives []*alternative
+	fallback     http.Handler
+}
+
+type alternative struct {
+	matchers []Matcher
+	handler  http.Handler
+}
+
+//withAlternative returns the handler that results from registering handler
+//with matchers where existing is already registered. A handler already
+//registered with the same matchers is replaced, or removed if handler is nil.
+//existing is not modified.
+func withAlternative(existing http.Handler, matchers []Matcher, handler http.Handler) http.Handler {
+	mh, _ := existing.(*matchingHandler)
+	if len(matchers) == 0 {
+		if mh == nil || len(mh.alternatives) == 0 {
+			return handler
+		}
+		return &matchingHandler{alternatives: mh.alternatives, fallback: handler}
+	}
+	result := &matchingHandler{fallback: existing}
+	if mh != nil {
+		result.fallback = mh.fallback
+		for _, alt := range mh.alternatives {
+			if !equalMatchers(alt.matchers, matchers) {
+				result.alternatives = append(result.alternatives, alt)
+			}
+		}
+	}
+	if handler != nil {
+		result.alternatives = append(result.alternatives, &alternative{matchers: matchers, handler: handler})
+	}
+	if len(result.alternatives) == 0 {
+		return result.fallback
+	}
+	sort.SliceStable(result.alternatives, func(i, j int) bool {
+		return len(result.alternatives[i].matchers) > len(result.alternatives[j].matchers)
+	})
+	return result
+}
+
+//equalMatchers returns whether a and b are the same Matchers in the same
+//order. Matchers are compared with ==, so Matchers of types that are not
+//comparable are never equal.
+func equalMatchers(a, b []Matcher) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.TypeOf(a[i]).Comparable() || reflect.TypeOf(a[i]) != reflect.TypeOf(b[i]) || a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//unwrapHandler returns the handler served when no alternative registered with
+//Matchers matches, and the alternatives, if handler is a *matchingHandler.
+func unwrapHandler(handler http.Handler) (http.Handler, []*alternative) {
+	if mh, ok := handler.(*matchingHandler); ok {
+		return mh.fallback, mh.alternatives
+	}
+	return handler, nil
+}
+
+//choose returns the handler that r should be served by.
+func (mh *matchingHandler) choose(r *http.Request) (http.Handler, error) {
+	var err error
+	for _, alt := range mh.alternatives {
+		failed := firstFailedMatcher(alt.matchers, r)
+		if failed == nil {
+			return alt.handler, nil
+		}
+		if err == nil {
+			err = ErrNotMatched(failed.MismatchStatus())
+		}
+	}
+	if mh.fallback != nil {
+		return mh.fallback, nil
+	}
+	return nil, err
+}
+
+func (mh *matchingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, err := mh.choose(r)
+	if err != nil {
+		handler = err.(ErrNotMatched)
+	}
+	handler.ServeHTTP(w, r)
+}
+
+func firstFailedMatcher(matchers []Matcher, r *http.Request) Matcher {
+	for _, m := range matchers {
+		if !m.Match(r) {
+			return m
+		}
+	}
+	return nil
+}
+
+//chooseHandler returns handler, or the handler it chooses for r if it is a
+//*matchingHandler.
+func chooseHandler(handler http.Handler, r *http.Request) (http.Handler, error) {
+	if mh, ok := handler.(*matchingHandler); ok {
+		return mh.choose(r)
+	}
+	return handler, nil
+}
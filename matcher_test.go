@@ -0,0 +1,127 @@
+package httpmux
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoute_Match_ChoosesHandlersByMatchers(t *testing.T) {
+	m := New()
+
+	items := m.SubRoute("/items")
+	items.Get(TestHandler("HTML"))
+	items.Match(Accept("application/json")).Get(TestHandler("JSON"))
+	items.Match(Accept("application/json"), Query("pretty", "")).Get(TestHandler("PRETTY_JSON"))
+	items.Match(ContentType("application/json")).Post(TestHandler("CREATE_JSON"))
+	items.Match(ContentType("application/x-www-form-urlencoded")).Post(TestHandler("CREATE_FORM"))
+
+	secure := m.SubRoute("/secure")
+	secure.Match(Scheme("https")).Handle(TestHandler("SECURE"))
+
+	versioned := m.SubRoute("/versioned")
+	versioned.Match(Header("X-Version", "2")).Get(TestHandler("V2"))
+	versioned.Match(Accept("text/csv")).Get(TestHandler("CSV"))
+
+	tests := []struct {
+		method  string
+		path    string
+		headers map[string]string
+		tls     bool
+
+		status int
+		body   string
+	}{
+		{"GET", "/items", nil, false, 200, "JSON"},
+		{"GET", "/items", map[string]string{"Accept": "text/html"}, false, 200, "HTML"},
+		{"GET", "/items", map[string]string{"Accept": "text/html, application/*;q=0.5"}, false, 200, "JSON"},
+		{"GET", "/items", map[string]string{"Accept": "application/json;q=0"}, false, 200, "HTML"},
+		{"GET", "/items?pretty", map[string]string{"Accept": "application/json"}, false, 200, "PRETTY_JSON"},
+		{"POST", "/items", map[string]string{"Content-Type": "application/json; charset=utf-8"}, false, 200, "CREATE_JSON"},
+		{"POST", "/items", map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, false, 200, "CREATE_FORM"},
+		{"POST", "/items", map[string]string{"Content-Type": "text/plain"}, false, 415, "Unsupported Media Type\n"},
+		{"PUT", "/items", nil, false, 405, "Method Not Allowed\n"},
+		{"GET", "/secure", nil, false, 400, "Bad Request\n"},
+		{"GET", "/secure", nil, true, 200, "SECURE"},
+		{"GET", "/versioned", map[string]string{"X-Version": "2", "Accept": "text/csv"}, false, 200, "V2"},
+		{"GET", "/versioned", map[string]string{"Accept": "text/csv"}, false, 200, "CSV"},
+		{"GET", "/versioned", map[string]string{"X-Version": "1", "Accept": "text/html"}, false, 400, "Bad Request\n"},
+	}
+
+	for i, test := range tests {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range test.headers {
+			r.Header.Set(name, value)
+		}
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.status || w.Body.String() != test.body {
+			t.Errorf("%v: response = %v %q WANT %v %q", i, w.Code, w.Body.String(), test.status, test.body)
+		}
+	}
+}
+
+func TestMux_ServeHTTP_UsesNotMatchedHandler(t *testing.T) {
+	m := New()
+	m.NotMatchedHandler = TestHandler("NOT_MATCHED")
+	m.SubRoute("/json").Match(Accept("application/json")).Get(TestHandler("JSON"))
+
+	w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ := http.NewRequest("GET", "/json", nil)
+	r.Header.Set("Accept", "text/html")
+	m.ServeHTTP(w, r)
+
+	if body := w.Body.String(); body != "NOT_MATCHED" {
+		t.Errorf("w.Body = %q WANT %q", body, "NOT_MATCHED")
+	}
+}
+
+func TestRoute_Match_HandleReplacesAndRemovesHandlersWithTheSameMatchers(t *testing.T) {
+	m := New()
+	items := m.SubRoute("/items")
+	items.Get(TestHandler("HTML"))
+	json := items.Match(Accept("application/json"))
+	json.Get(TestHandler("JSON"))
+	json.Get(TestHandler("NEW_JSON"))
+
+	serve := func() string {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, _ := http.NewRequest("GET", "/items", nil)
+		r.Header.Set("Accept", "application/json")
+		m.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	if body := serve(); body != "NEW_JSON" {
+		t.Errorf("w.Body = %q WANT %q", body, "NEW_JSON")
+	}
+	json.Handle(nil, "GET")
+	if body := serve(); body != "HTML" {
+		t.Errorf("w.Body = %q WANT %q", body, "HTML")
+	}
+	m.Walk(func(info RouteInfo) error {
+		if len(info.Matched) != 0 {
+			t.Errorf("info.Matched = %v WANT empty", info.Matched)
+		}
+		return nil
+	})
+}
+
+func TestRoute_Match_panicsWithNilMatchers(t *testing.T) {
+	m := New()
+	defer func() {
+		if err := recover(); err != ErrNilMatcher(1) {
+			t.Errorf("recover() = %v WANT %v", err, ErrNilMatcher(1))
+		}
+	}()
+	m.Root().Match(Accept("application/json"), nil)
+}
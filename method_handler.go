@@ -9,6 +9,11 @@ import (
 type methodHandler struct {
 	all     http.Handler
 	methods map[string]http.Handler
+
+	//noAutoHead and noAutoOptions disable the methodOptions of the same name
+	//for this methodHandler only.
+	noAutoHead    bool
+	noAutoOptions bool
 }
 
 func newMethodHandler() *methodHandler {
@@ -16,22 +21,40 @@ func newMethodHandler() *methodHandler {
 }
 
 func (mh *methodHandler) put(handler http.Handler, methods ...string) {
+	mh.putMatching(nil, handler, methods...)
+}
+
+//putMatching registers handler for methods to be served for requests that
+//satisfy matchers. See Route.Match.
+func (mh *methodHandler) putMatching(matchers []Matcher, handler http.Handler, methods ...string) {
 	if len(methods) == 0 {
-		mh.all = handler
+		mh.all = withAlternative(mh.all, matchers, handler)
 		return
 	}
 	if mh.methods == nil {
 		mh.methods = map[string]http.Handler{}
 	}
 	for _, method := range cleanMethods(methods) {
-		if handler == nil {
+		if result := withAlternative(mh.methods[method], matchers, handler); result == nil {
 			delete(mh.methods, method)
 		} else {
-			mh.methods[method] = handler
+			mh.methods[method] = result
 		}
 	}
 }
 
+//remove removes the handlers for methods, or all handlers if no methods are
+//given.
+func (mh *methodHandler) remove(methods ...string) {
+	if len(methods) == 0 {
+		mh.all, mh.methods = nil, nil
+		return
+	}
+	for _, method := range cleanMethods(methods) {
+		delete(mh.methods, method)
+	}
+}
+
 func (mh *methodHandler) get(cleanedMethod string) (http.Handler, error) {
 	if mh == nil {
 		return nil, ErrNotFound
@@ -52,6 +75,102 @@ func (mh *methodHandler) get(cleanedMethod string) (http.Handler, error) {
 	return nil, ErrMethodNotAllowed(mh.listMethods())
 }
 
+//methodOptions are the methods that a methodHandler handles automatically
+//when they are not explicitly registered.
+type methodOptions struct {
+	autoHead    bool
+	autoOptions bool
+}
+
+//getWithOptions returns the handler for r's method with HEAD and OPTIONS
+//requests handled as described by o. Explicitly registered handlers always
+//take precedence over the automatic ones. Handlers registered with Matchers
+//are chosen from with r.
+func (mh *methodHandler) getWithOptions(r *http.Request, o methodOptions) (http.Handler, error) {
+	o.autoHead = o.autoHead && !mh.noAutoHead
+	o.autoOptions = o.autoOptions && !mh.noAutoOptions
+	cleanedMethod := r.Method
+	handler, err := mh.get(cleanedMethod)
+	errMNA, ok := err.(ErrMethodNotAllowed)
+	if !ok {
+		if err != nil {
+			return nil, err
+		}
+		return chooseHandler(handler, r)
+	}
+
+	allowed := o.allowedMethods(errMNA)
+	switch {
+	case o.autoHead && cleanedMethod == http.MethodHead:
+		if getHandler := mh.methods[http.MethodGet]; getHandler != nil {
+			getHandler, err := chooseHandler(getHandler, r)
+			if err != nil {
+				return nil, err
+			}
+			return &headHandler{getHandler}, nil
+		}
+	case o.autoOptions && cleanedMethod == http.MethodOptions:
+		return &optionsHandler{allow: allowed.Header()}, nil
+	}
+	return nil, allowed
+}
+
+//allowedMethods returns registered with the methods that o handles
+//automatically added.
+func (o methodOptions) allowedMethods(registered ErrMethodNotAllowed) ErrMethodNotAllowed {
+	result := append(ErrMethodNotAllowed{}, registered...)
+	if o.autoHead && containsMethod(result, http.MethodGet) && !containsMethod(result, http.MethodHead) {
+		result = append(result, http.MethodHead)
+	}
+	if o.autoOptions && !containsMethod(result, http.MethodOptions) {
+		result = append(result, http.MethodOptions)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+//headHandler serves HEAD requests with a GET handler while discarding the
+//response body.
+type headHandler struct {
+	get http.Handler
+}
+
+func (h *headHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.get.ServeHTTP(headResponseWriter{w}, r)
+}
+
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+//optionsHandler responds to OPTIONS requests with No Content and an Allow
+//header.
+type optionsHandler struct {
+	allow string
+}
+
+func (h *optionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderAllow, h.allow)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (mh *methodHandler) handlers() *methodHandler {
+	return mh
+}
+
 func (mh *methodHandler) isRegistered() bool {
 	return mh.all != nil || len(mh.methods) > 0
 }
@@ -0,0 +1,160 @@
+package httpmux
+
+import "net/http"
+
+//Middleware wraps an http.Handler to add behavior before or after it is
+//served.
+type Middleware func(http.Handler) http.Handler
+
+//Use adds middleware to m that wraps every request that m serves, including
+//those served by the NotFoundHandler, the MethodNotAllowedHandler, and
+//redirects. The first middleware added is the outermost.
+//
+//Requests are routed before the middleware is applied to the handler that
+//will serve them, so RouteFrom and VariablesFrom may be used with the
+//request's Context. Like the middleware of Routes, it is applied for each
+//request.
+//
+//Use may be called while m is serving requests. Requests that are already
+//being served are unaffected.
+func (m *Mux) Use(middleware ...Middleware) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	current, _ := m.middleware.Load().([]Middleware)
+	next := make([]Middleware, 0, len(current)+len(middleware))
+	next = append(append(next, current...), middleware...)
+	m.middleware.Store(next)
+}
+
+//loadMiddleware returns the middleware added with Mux.Use. It must not be
+//modified.
+func (m *Mux) loadMiddleware() []Middleware {
+	result, _ := m.middleware.Load().([]Middleware)
+	return result
+}
+
+//wrapMiddleware wraps handler in middleware so that the first middleware is
+//the outermost.
+func wrapMiddleware(handler http.Handler, middleware []Middleware) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+//Use adds middleware to r that wraps every handler registered at or beneath r,
+//regardless of whether the handler was registered before or after the call.
+//Only whole path segments are beneath r, so middleware added to "/admin"
+//wraps the handlers of "/admin" and "/admin/users", but not of
+//"/administrators".
+//It also wraps the MethodNotAllowedHandler for requests that are found at or
+//beneath r.
+//
+//Middleware is ordered from the outermost to the innermost as: middleware
+//added with Mux.Use, then middleware of Routes closer to the root before
+//middleware of their sub routes, then middleware added to the same Route in
+//the order it was added.
+//
+//If r's pattern has optional parts, then middleware wraps the handlers at or
+//beneath any of the paths it expands into, and wraps each handler only once.
+func (r *Route) Use(middleware ...Middleware) *Route {
+	err := r.table.update(func(tree *routeTree) error {
+		for _, route := range r.outermost() {
+			n, err := route.appendTo(tree)
+			if err != nil {
+				return err
+			}
+			ownDescendants(n, tree.gen)
+			attrs := n.attrs()
+			attrs.middleware = append(attrs.middleware, middleware...)
+		}
+		return nil
+	})
+	panicIfInvalid(err)
+	return r
+}
+
+//outermost returns the Routes of r.all() that are not beneath another one of
+//them in the routing tree.
+func (r *Route) outermost() []*Route {
+	all := r.all()
+	result := make([]*Route, 0, len(all))
+	for _, route := range all {
+		beneath := false
+		for _, other := range all {
+			if other != route && len(other.pattern) < len(route.pattern) && patternBeneath(route.pattern, other.pattern) {
+				beneath = true
+				break
+			}
+		}
+		if !beneath {
+			result = append(result, route)
+		}
+	}
+	return result
+}
+
+//setInherited sets what the nodes of tree inherit from the nodes they are
+//beneath, see attributes.chain and attributes.allMeta.
+//
+//Only the nodes owned by tree are visited. The nodes tree shares with the tree
+//it was cloned from inherit the same as before, since changing the middleware
+//or metadata of a node makes all of its descendants owned, see ownDescendants.
+func (tree *routeTree) setInherited() {
+	setInherited(tree.root, "", nil, tree.gen)
+	for _, h := range tree.hosts {
+		setInherited(h.root, "", nil, tree.gen)
+	}
+}
+
+//scope is the middleware and metadata of the node with pattern.
+type scope struct {
+	pattern    string
+	middleware []Middleware
+	meta       map[string]interface{}
+}
+
+//setInherited sets the chain and allMeta of n and its descendants owned by the
+//tree of generation gen, where pattern is the full pattern of n and scopes are
+//those of n's ancestors.
+func setInherited(n node, pattern string, scopes []scope, gen uint64) {
+	attrs := n.attrs()
+	if len(attrs.middleware) > 0 || len(attrs.meta) > 0 {
+		scopes = append(scopes[:len(scopes):len(scopes)], scope{pattern, attrs.middleware, attrs.meta})
+	}
+
+	attrs.chain, attrs.allMeta = nil, nil
+	for _, s := range scopes {
+		if patternBeneath(pattern, s.pattern) {
+			attrs.chain = append(attrs.chain, s.middleware...)
+			attrs.allMeta = mergeMeta(attrs.allMeta, s.meta)
+		}
+	}
+
+	forEachChild(n, pattern, func(child node, childPattern string) bool {
+		if child.attrs().gen == gen {
+			setInherited(child, childPattern, scopes, gen)
+		}
+		return true
+	})
+}
+
+//mergeMeta returns the metadata of base with that of over added to it,
+//replacing the values of equal keys. Neither base nor over is modified.
+func mergeMeta(base, over map[string]interface{}) map[string]interface{} {
+	if len(over) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return over
+	}
+	result := make(map[string]interface{}, len(base)+len(over))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range over {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func traceMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestMux_Use_AppliesMiddlewareInOrder(t *testing.T) {
+	m := New()
+	m.Use(traceMiddleware("mux1"), traceMiddleware("mux2"))
+
+	api := m.SubRoute("/api")
+	api.Use(traceMiddleware("api"))
+
+	users := api.SubRoute("/users")
+	users.SubRoute("/:id").Handle(TestHandler("USER"), "GET")
+	users.Use(traceMiddleware("users1")).Use(traceMiddleware("users2"))
+
+	m.Handle("/other", TestHandler("OTHER"))
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		trace  []string
+	}{
+		{"GET", "/api/users/1", 200, []string{"mux1", "mux2", "api", "users1", "users2"}},
+		{"POST", "/api/users/1", 405, []string{"mux1", "mux2", "api", "users1", "users2"}},
+		{"GET", "/api/none", 404, []string{"mux1", "mux2"}},
+		{"GET", "/other", 200, []string{"mux1", "mux2"}},
+	}
+
+	for i, test := range tests {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, _ := http.NewRequest(test.method, test.path, nil)
+		w.Context = r.Context()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%v: w.Code = %v WANT %v", i, w.Code, test.status)
+		}
+		if trace := w.Header()["X-Trace"]; !reflect.DeepEqual(trace, test.trace) {
+			t.Errorf("%v: trace = %v WANT %v", i, strings.Join(trace, ","), strings.Join(test.trace, ","))
+		}
+	}
+}
+
+func TestMux_Use_SeesTheMatchedRoute(t *testing.T) {
+	m := New()
+	var patterns []string
+	m.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, _ := RouteFrom(r.Context())
+			patterns = append(patterns, route.Pattern+" "+VariableValue(r.Context(), "id"))
+			next.ServeHTTP(w, r)
+		})
+	})
+	m.Handle("/users/:id", TestHandler("USER"))
+	m.Handle("/static", TestHandler("STATIC"))
+
+	for _, path := range []string{"/users/1", "/static", "/none"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(&TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}, r)
+	}
+
+	if want := []string{"/users/:id 1", "/static ", " "}; !reflect.DeepEqual(patterns, want) {
+		t.Errorf("patterns = %q WANT %q", patterns, want)
+	}
+}
+
+func TestRoute_Use_SurvivesRemove(t *testing.T) {
+	m := New()
+
+	group := m.SubRoute("/group")
+	group.Use(traceMiddleware("group"))
+	m.Handle("/group/a", TestHandler("A"))
+	m.Handle("/group/b", TestHandler("B"))
+
+	m.Remove("/group/a")
+
+	w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ := http.NewRequest("GET", "/group/b", nil)
+	m.ServeHTTP(w, r)
+
+	if trace := w.Header()["X-Trace"]; !reflect.DeepEqual(trace, []string{"group"}) {
+		t.Errorf("trace = %v WANT [group]", trace)
+	}
+}
+
+func TestRoute_Use_OnlyWrapsWholeSegmentsBeneath(t *testing.T) {
+	m := New()
+
+	m.SubRoute("/admin").Use(traceMiddleware("admin"))
+	m.Handle("/admin", TestHandler("ADMIN"))
+	m.Handle("/admin/users", TestHandler("USERS"))
+	m.Handle("/administrators", TestHandler("ADMINISTRATORS"))
+
+	m.SubRoute("/files/:name").Use(traceMiddleware("name"))
+	m.Handle("/files/:name", TestHandler("FILE"))
+	m.Handle("/files/:name.json", TestHandler("JSON"))
+
+	m.SubRoute("/v[1]").Use(traceMiddleware("version"))
+	m.Handle("/v1", TestHandler("V1"))
+
+	tests := []struct {
+		path  string
+		trace []string
+	}{
+		{"/admin", []string{"admin"}},
+		{"/admin/users", []string{"admin"}},
+		{"/administrators", nil},
+		{"/files/a", []string{"name"}},
+		{"/files/a.json", nil},
+		{"/v1", []string{"version"}},
+	}
+
+	for i, test := range tests {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, _ := http.NewRequest("GET", test.path, nil)
+		w.Context = r.Context()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%v: w.Code = %v WANT %v", i, w.Code, http.StatusOK)
+		}
+		if trace := w.Header()["X-Trace"]; !reflect.DeepEqual(trace, test.trace) {
+			t.Errorf("%v: trace = %v WANT %v", i, strings.Join(trace, ","), strings.Join(test.trace, ","))
+		}
+	}
+}
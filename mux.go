@@ -3,30 +3,129 @@ package httpmux
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	muxpath "github.com/gogolfing/httpmux/path"
 )
 
+//variablesKey is the type of the Context key that request variables are stored
+//under. It is unexported so that no other package can collide with it.
 type variablesKey int
 
 const variablesKeyValue variablesKey = 1
 
+//asteriskTarget is the request target of server-wide OPTIONS requests, which
+//is not a path and is never redirected.
+const asteriskTarget = "*"
+
+//Mux is an http.Handler that dispatches requests to the handlers registered on
+//its Routes.
+//
+//Routes may be registered while m is serving requests. To replace many routes
+//at once, register them on a separate Mux and publish them with Swap.
 type Mux struct {
-	root *Route
+	table atomic.Value //*routeTable
+
+	lock       sync.Mutex
+	middleware atomic.Value //[]Middleware
 
 	AllowTrailingSlashes bool
 
 	DisallowSettingAllowMethodHeader bool
 	MethodNotAllowedHandler          http.Handler
 
+	//AutoHead causes HEAD requests to be served by a Route's GET handler, with
+	//the response body discarded, if no HEAD handler is registered.
+	//See Route.DisableAutoHead to opt a Route out.
+	AutoHead bool
+
+	//AutoOptions causes OPTIONS requests to be answered with No Content and an
+	//Allow header if no OPTIONS handler is registered.
+	//See Route.DisableAutoOptions to opt a Route out.
+	//
+	//With AutoHead and AutoOptions, the methods handled automatically are also
+	//included in the Allow header of Method Not Allowed responses.
+	//Registering a handler for HEAD, OPTIONS, or all methods on a Route
+	//overrides the automatic behavior for that Route.
+	AutoOptions bool
+
+	//RedirectCleanPath causes requests whose paths are not clean, see
+	//path.Clean, to be redirected to the cleaned path instead of being served
+	//at the cleaned path. The request target "*" of OPTIONS requests is not
+	//redirected.
+	RedirectCleanPath bool
+
+	//RedirectTrailingSlash causes requests that are not found, but would be
+	//served by a handler with a trailing slash added or removed, to be
+	//redirected to that path. It takes precedence over AllowTrailingSlashes.
+	//
+	//Redirects preserve the query string and use Moved Permanently for GET and
+	//HEAD requests, and Permanent Redirect otherwise.
+	RedirectTrailingSlash bool
+
+	//CaseInsensitive causes the static parts of request paths to be matched
+	//ignoring the case of ASCII letters. Variable Values keep the case of the
+	//request. Routes whose static parts differ only in case may still be
+	//registered, and the exact match is preferred.
+	CaseInsensitive bool
+
+	//RedirectCanonicalCase causes requests that are only found ignoring case
+	//to be redirected to the path with the registered case instead of being
+	//served. It takes effect only if CaseInsensitive is set.
+	RedirectCanonicalCase bool
+
+	//UseEscapedPath causes requests to be matched against their escaped path,
+	//see url.URL.EscapedPath, instead of their decoded path. Only literal
+	//slashes separate segments, so an escaped slash, "%2F", may be part of a
+	//segment variable's value.
+	//
+	//Variable Values are unescaped and their escaped forms are available as
+	//RawValue. Constraints are matched against the unescaped Values, while
+	//the static parts of patterns are matched against the escaped path, so
+	//they should be registered escaped if they contain characters that are
+	//escaped in paths.
+	UseEscapedPath bool
+
 	NotFoundHandler http.Handler
+
+	//ProblemDetails causes the default error handlers, which respond with
+	//text/plain, to respond with RFC 7807 application/problem+json bodies
+	//instead, see Problem. Responses are content negotiated against the Accept
+	//header, so clients that prefer text/plain or text/html still receive text.
+	//Error handlers that are ErrStatusHandlers or ErrNotMatcheds, such as the
+	//MethodNotAllowedHandler that New sets, are replaced. Other handlers are
+	//unaffected.
+	ProblemDetails bool
+
+	//NotMatchedHandler is served for ErrNotMatched errors. If it is nil, then
+	//the ErrNotMatched itself is served.
+	NotMatchedHandler http.Handler
 }
 
 func New() *Mux {
-	return &Mux{
-		root: newRootRoute(),
+	m := &Mux{
 		MethodNotAllowedHandler: ErrStatusHandler(http.StatusMethodNotAllowed),
 	}
+	m.table.Store(newRouteTable(m))
+	return m
+}
+
+func (m *Mux) routes() *routeTable {
+	return m.table.Load().(*routeTable)
+}
+
+//Swap atomically replaces all of the routes served by m with a copy of the
+//routes registered on next. Only routes are swapped, m's other fields are
+//unchanged.
+//
+//Requests that have already found their handler are unaffected. Routes
+//previously obtained from m are no longer served by m after the call, and
+//registering on next, or on Routes obtained from next, does not affect m.
+func (m *Mux) Swap(next *Mux) {
+	m.table.Store(next.routes().copy(m))
 }
 
 func (m *Mux) HandleFunc(path string, handlerFunc http.HandlerFunc, methods ...string) *Route {
@@ -37,55 +136,221 @@ func (m *Mux) Handle(path string, handler http.Handler, methods ...string) *Rout
 	return m.SubRoute(path).Handle(handler, methods...)
 }
 
+//TryHandle is the same as Handle except that an *ErrInvalidRoute is returned
+//instead of panicking when path cannot be registered.
+func (m *Mux) TryHandle(path string, handler http.Handler, methods ...string) (*Route, error) {
+	route, err := m.TrySubRoute(path)
+	if err != nil {
+		return nil, err
+	}
+	return route.Handle(handler, methods...), nil
+}
+
 func (m *Mux) Root() *Route {
-	return m.root
+	return m.routes().root
 }
 
 func (m *Mux) SubRoute(path string) *Route {
-	return m.root.SubRoute(path)
+	return m.Root().SubRoute(path)
+}
+
+//TrySubRoute is the same as SubRoute except that an *ErrInvalidRoute is
+//returned instead of panicking when path cannot be registered.
+func (m *Mux) TrySubRoute(path string) (*Route, error) {
+	return m.Root().TrySubRoute(path)
+}
+
+//URL returns the escaped path of the Route registered with name, or its
+//scheme-relative URL if it is beneath a host pattern. See Route.URL.
+func (m *Mux) URL(name string, vars ...*Variable) (string, error) {
+	route, ok := m.routes().namedRoute(name)
+	if !ok {
+		return "", ErrUnknownRouteName(name)
+	}
+	return route.URL(vars...)
 }
 
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	handler, vars, err := m.root.findHandler(r, m.getFoundMatcher())
-	if err != nil {
-		m.serveError(w, r, err)
-		return
+	middleware := m.loadMiddleware()
+	handler, r := m.route(r, len(middleware) > 0)
+	wrapMiddleware(handler, middleware).ServeHTTP(w, r)
+}
+
+//route returns the handler that r is served by, wrapped in the middleware of
+//the Route it was found at, and r with the Route and its Variables added to its
+//Context if it was found at one.
+//
+//The Context is only replaced if the Route has Variables, Meta, or middleware,
+//or if hasMiddleware is true, so that requests found at plain static routes
+//are served without allocating.
+func (m *Mux) route(r *http.Request, hasMiddleware bool) (http.Handler, *http.Request) {
+	mt := newMatch()
+	defer mt.release()
+
+	d := m.decide(r, mt)
+	if len(d.redirect) > 0 {
+		return m.redirectHandler(r, d.redirect, d.redirectStatus), r
+	}
+	if !d.matched {
+		return m.errorHandler(d.err), r
+	}
+	handler := d.handler
+	if d.err != nil {
+		handler = m.errorHandler(d.err)
+	}
+	if !hasMiddleware && len(mt.vars) == 0 && len(mt.meta) == 0 && len(mt.middleware) == 0 {
+		return handler, r
+	}
+	return mt.wrap(handler), r.WithContext(newVariablesContext(r.Context(), mt))
+}
+
+//decision is how a request is served, see Mux.decide.
+type decision struct {
+	path string
+
+	//matched is whether the request was found at a Route, whose handler or
+	//error is handler or err.
+	matched bool
+	handler http.Handler
+	err     error
+
+	//redirect is the path the request is redirected to with redirectStatus,
+	//or empty if it is not redirected.
+	redirect       string
+	redirectStatus int
+}
+
+//decide searches for r with mt and returns how r is served. It is used by
+//both ServeHTTP and Explain, so that they always agree.
+func (m *Mux) decide(r *http.Request, mt *match) decision {
+	d := decision{path: m.requestPath(r)}
+
+	if m.RedirectCleanPath && d.path != asteriskTarget {
+		if cleaned := muxpath.Clean(d.path); cleaned != d.path {
+			return d.redirectTo(r, cleaned)
+		}
+	}
+
+	mt.escaped = m.UseEscapedPath
+	mt.ignoreCase = m.CaseInsensitive
+
+	tree := m.routes().load()
+	d.handler, d.err = tree.findHandler(r, d.path, m.getFoundMatcher(), m.getMethodOptions(), mt)
+	if d.err == ErrNotFound {
+		if m.RedirectTrailingSlash {
+			//The search for the redirect is not part of the trace.
+			trace := mt.trace
+			mt.trace = nil
+			redirectPath, ok := m.findTrailingSlashRedirect(tree, r, d.path, mt)
+			mt.trace = trace
+			if ok {
+				return d.redirectTo(r, redirectPath)
+			}
+		}
+		return d
+	}
+
+	d.matched = true
+	if m.RedirectCanonicalCase && len(mt.folds) > 0 {
+		return d.redirectTo(r, mt.canonicalPath(muxpath.Clean(d.path)))
+	}
+	return d
+}
+
+//redirectTo returns d with r redirected to path. GET and HEAD requests are
+//redirected with 301 Moved Permanently, and others with 308 Permanent Redirect
+//so that their method and body are kept.
+func (d decision) redirectTo(r *http.Request, path string) decision {
+	d.redirect = path
+	d.redirectStatus = http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		d.redirectStatus = http.StatusMovedPermanently
+	}
+	return d
+}
+
+//requestPath returns the path of r that is matched against routes.
+func (m *Mux) requestPath(r *http.Request) string {
+	if m.UseEscapedPath {
+		return r.URL.EscapedPath()
+	}
+	return r.URL.Path
+}
+
+//findTrailingSlashRedirect returns the cleaned request path with its trailing
+//slash toggled, and whether r would be served by a handler at that path.
+func (m *Mux) findTrailingSlashRedirect(tree *routeTree, r *http.Request, path string, mt *match) (string, bool) {
+	path = muxpath.Clean(path)
+	if path == muxpath.Slash {
+		return "", false
+	}
+	if strings.HasSuffix(path, muxpath.Slash) {
+		path = path[:len(path)-1]
+	} else {
+		path += muxpath.Slash
 	}
-	r = m.mapVariables(r, vars)
-	handler.ServeHTTP(w, r)
+	_, err := tree.findHandler(r, path, m.getFoundMatcher(), m.getMethodOptions(), mt)
+	mt.clear()
+	return path, err == nil
+}
+
+//redirectHandler returns a handler that redirects r to path with status. path
+//is escaped if m has UseEscapedPath set.
+func (m *Mux) redirectHandler(r *http.Request, path string, status int) http.Handler {
+	location := &url.URL{Path: path, RawQuery: r.URL.RawQuery}
+	if m.UseEscapedPath {
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			location.Path, location.RawPath = unescaped, path
+		}
+	}
+	return http.RedirectHandler(location.String(), status)
 }
 
 func (m *Mux) getFoundMatcher() foundMatcher {
-	if m.AllowTrailingSlashes {
+	if m.AllowTrailingSlashes && !m.RedirectTrailingSlash {
 		return stringFoundMatcher(muxpath.Slash)
 	}
 	return stringFoundMatcher("")
 }
 
-func (m *Mux) serveError(w http.ResponseWriter, r *http.Request, err error) {
-	handler := m.getErrorHandler(err)
-	if handler == nil {
-		return
+func (m *Mux) getMethodOptions() methodOptions {
+	return methodOptions{
+		autoHead:    m.AutoHead,
+		autoOptions: m.AutoOptions,
+	}
+}
+
+//errorHandler returns the handler that serves err, which does nothing if m has
+//no handler for err.
+func (m *Mux) errorHandler(err error) http.Handler {
+	if handler := m.getErrorHandler(err); handler != nil {
+		return handler
 	}
-	handler.ServeHTTP(w, r)
+	return http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
 }
 
 func (m *Mux) getErrorHandler(err error) http.Handler {
 	if errMNA, ok := err.(ErrMethodNotAllowed); ok {
 		var result http.Handler
 		if m.MethodNotAllowedHandler != nil {
-			result = m.MethodNotAllowedHandler
+			result = m.problemOr(m.MethodNotAllowedHandler, errMNA)
 		}
 		if !m.DisallowSettingAllowMethodHeader {
 			return &setHeaderHandler{name: HeaderAllow, value: errMNA.Header(), wrapped: result}
 		}
 		return result
 	}
+	if errNM, ok := err.(ErrNotMatched); ok {
+		if m.NotMatchedHandler != nil {
+			return m.problemOr(m.NotMatchedHandler, errNM)
+		}
+		return m.problemOr(errNM, errNM)
+	}
 	if err == ErrNotFound {
 		if m.NotFoundHandler != nil {
-			return m.NotFoundHandler
+			return m.problemOr(m.NotFoundHandler, ErrNotFound)
 		}
-		return ErrNotFound
+		return m.problemOr(ErrNotFound, ErrNotFound)
 	}
 	return nil
 }
@@ -104,32 +369,83 @@ func (shh *setHeaderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (m *Mux) mapVariables(r *http.Request, vars []*Variable) *http.Request {
-	if len(vars) == 0 {
-		return r
-	}
+//MatchedRoute describes the Route that a request was found at.
+type MatchedRoute struct {
+	//Host is the host pattern of the Route, see Mux.Host, or empty if it has
+	//none.
+	Host string
 
-	ctx := context.WithValue(r.Context(), variablesKeyValue, vars)
-	for _, v := range vars {
-		ctx = context.WithValue(ctx, v.Name, v.Value)
+	//Pattern is the full pattern of the Route. If the Route was created with
+	//optional parts, then it is the pattern that the request was found at.
+	Pattern string
+
+	//Meta is the metadata of the Route, see Route.WithMeta. It must not be
+	//modified.
+	Meta map[string]interface{}
+}
+
+//RouteFrom returns the MatchedRoute of the request whose Context is c.
+//
+//ok is false if the request was not found at a Route, or if it was
+//redirected. To serve plain static routes without allocating, the Route is
+//only added to the Context if it has Variables or Meta, or if the Mux or the
+//Route has middleware, so ok is also false in the handler of a Route with none
+//of them.
+func RouteFrom(c context.Context) (route MatchedRoute, ok bool) {
+	vc := variablesFrom(c)
+	if vc == nil {
+		return MatchedRoute{}, false
 	}
-	return r.WithContext(ctx)
+	return vc.route, true
 }
 
+//VariablesFrom returns copies of the Variables found for the request whose
+//Context is c, in the order they appear in the request, or nil if there are
+//none.
 func VariablesFrom(c context.Context) []*Variable {
-	vars, _ := c.Value(variablesKeyValue).([]*Variable)
-	return vars
+	vc := variablesFrom(c)
+	if vc == nil || len(vc.vars) == 0 {
+		return nil
+	}
+	result := make([]*Variable, len(vc.vars))
+	copies := make([]Variable, len(vc.vars))
+	for i := range vc.vars {
+		copies[i] = vc.vars[i]
+		result[i] = &copies[i]
+	}
+	return result
 }
 
+//VariableFrom returns the Variable named name in c, or nil if there is none.
+//
+//Reading a Variable's Value with a VarName as the key of c.Value, e.g.
+//c.Value(VarName("id")), is only supported for compatibility with earlier
+//versions. New code should use VariableFrom or VariableValue.
 func VariableFrom(c context.Context, name string) *Variable {
 	v, _ := VariableFromOk(c, name)
 	return v
 }
 
 func VariableFromOk(c context.Context, name string) (*Variable, bool) {
-	value, ok := c.Value(VarName(name)).(string)
+	vc := variablesFrom(c)
+	if vc == nil {
+		return nil, false
+	}
+	v, ok := vc.variable(VarName(name))
 	if !ok {
-		return nil, ok
+		return nil, false
+	}
+	return &v, true
+}
+
+//VariableValue returns the Value of the Variable named name found for the
+//request whose Context is c, or the empty string if there is no such Variable.
+//Unlike VariableFrom, it does not allocate.
+func VariableValue(c context.Context, name string) string {
+	vc := variablesFrom(c)
+	if vc == nil {
+		return ""
 	}
-	return &Variable{VarName(name), value}, ok
+	v, _ := vc.variable(VarName(name))
+	return v.Value
 }
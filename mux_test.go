@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+
+	muxpath "github.com/gogolfing/httpmux/path"
 )
 
 const (
@@ -36,6 +38,89 @@ type ServeHTTPTest struct {
 }
 
 func TestMux_Handle_panicsWithBadRoutingConfiguration(t *testing.T) {
+	m := New()
+	m.Handle("/users/:id", TestHandler("USER"))
+
+	defer func() {
+		if _, ok := recover().(*ErrUnequalVars); !ok {
+			t.Errorf("recover() must be an *ErrUnequalVars")
+		}
+	}()
+	m.Handle("/users/:userId", TestHandler("USER_ID"))
+}
+
+func TestMux_TryHandle_returnsErrorsWithConflictingPatterns(t *testing.T) {
+	m := New()
+	m.Handle("/users/:id/posts", TestHandler("POSTS"))
+	m.Handle("/files/*file", TestHandler("FILE"))
+	m.Handle("/static/index.html", TestHandler("INDEX"))
+	m.SubRoute("/empty/:name")
+
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{
+			"/users/:userId",
+			&ErrInvalidRoute{"/users/:userId", "/users/:id/posts", &ErrUnequalVars{"id", "userId"}},
+		},
+		{
+			"/users/:id{int}",
+			&ErrInvalidRoute{"/users/:id{int}", "/users/:id/posts", &ErrUnequalConstraints{"id", "", "int"}},
+		},
+		{
+			"/files/:file",
+			&ErrInvalidRoute{"/files/:file", "/files/*file", &ErrUnequalVars{"file", "file"}},
+		},
+		{
+			"/empty/:other",
+			&ErrInvalidRoute{"/empty/:other", "/empty/:name", &ErrUnequalVars{"name", "other"}},
+		},
+		{
+			"/x/:.json",
+			&ErrInvalidRoute{"/x/:.json", "", muxpath.ErrInvalidVariableName(":.json")},
+		},
+		{
+			"/users/:id/:user@host",
+			&ErrInvalidRoute{"/users/:id/:user@host", "", muxpath.ErrInvalidVariableName(":user@host")},
+		},
+		{
+			"/users/:id/posts/:post",
+			nil,
+		},
+		{
+			"/users/me",
+			nil,
+		},
+		{
+			"/static/:file",
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		_, err := m.TryHandle(test.path, TestHandler("TEST"))
+		if !reflect.DeepEqual(err, test.err) {
+			t.Errorf("%v: m.TryHandle(%q) = %v WANT %v", i, test.path, err, test.err)
+		}
+	}
+}
+
+func TestMux_TrySubRoute_leavesTheTreeUnchangedWhenReturningAnError(t *testing.T) {
+	m := New()
+
+	if _, err := m.TrySubRoute("/b/c/:x/d/:y{[}"); err == nil {
+		t.Fatal("m.TrySubRoute() must return an error for an invalid constraint")
+	}
+	if _, err := m.TrySubRoute("/users/:id[/posts/:post{[}]"); err == nil {
+		t.Fatal("m.TrySubRoute() must return an error for an invalid constraint")
+	}
+
+	for _, path := range []string{"/b/c/:z", "/users/:userId"} {
+		if _, err := m.TryHandle(path, TestHandler("TEST")); err != nil {
+			t.Errorf("m.TryHandle(%q) = %v WANT nil", path, err)
+		}
+	}
 }
 
 func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
@@ -100,7 +185,7 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 			Status: 200,
 			Body:   "CATCH_ALL",
 			Variables: []*Variable{
-				{"catchallvalue", "will/catch/)(*$)(@&$_ANYTHING_IN_URL_PATH"},
+				{Name: "catchallvalue", Value: "will/catch/)(*$)(@&$_ANYTHING_IN_URL_PATH", RawValue: "will/catch/%29%28%2A$%29%28@&$_ANYTHING_IN_URL_PATH"},
 			},
 		},
 		{
@@ -109,7 +194,7 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 			Status: 200,
 			Body:   "CATCH_ALL",
 			Variables: []*Variable{
-				{"catchallvalue", ""},
+				{Name: "catchallvalue", Value: "", RawValue: ""},
 			},
 		},
 		{
@@ -118,7 +203,7 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 			Status: 200,
 			Body:   "CATCH_ALL",
 			Variables: []*Variable{
-				{"catchallvalue", "oth"},
+				{Name: "catchallvalue", Value: "oth", RawValue: "oth"},
 			},
 		},
 		{
@@ -133,7 +218,7 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 			Status: 200,
 			Body:   "CATCH_ALL_OTHER_AGAIN",
 			Variables: []*Variable{
-				{"catchallagain", ""},
+				{Name: "catchallagain", Value: "", RawValue: ""},
 			},
 		},
 		{
@@ -142,7 +227,7 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 			Status: 200,
 			Body:   "CATCH_ALL_OTHER_AGAIN",
 			Variables: []*Variable{
-				{"catchallagain", "again"},
+				{Name: "catchallagain", Value: "again", RawValue: "again"},
 			},
 		},
 	}
@@ -153,6 +238,329 @@ func TestMux_ServeHTTP_ServesAllRoutesWithoutTrailingCorrectly(t *testing.T) {
 func TestMux_ServeHTTP_ServesAllRoutesWithAllowTrailingCorrectly(t *testing.T) {
 }
 
+func TestMux_ServeHTTP_ServesConstrainedVariablesCorrectly(t *testing.T) {
+	m := New()
+
+	m.Handle("/users/:id{int}", TestHandler("USER"))
+	m.Handle("/users/:id{int}/posts/:post{uuid}", TestHandler("POST"))
+	m.Handle("/tags/:slug{[a-z-]+}", TestHandler("TAG"))
+	m.Handle("/files/*file{.+\\.txt}", TestHandler("FILE"))
+	m.Handle("/dirs/*dir{[a-z]+/./[a-z]+}", TestHandler("DIR"))
+
+	tests := []*ServeHTTPTest{
+		{
+			Method: "GET",
+			Path:   "/users/123",
+			Status: 200,
+			Body:   "USER",
+			Variables: []*Variable{
+				{Name: "id", Value: "123", RawValue: "123", Constraint: "int"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "/users/abc",
+			Status: 404,
+			Body:   NotFoundBody,
+		},
+		{
+			Method: "GET",
+			Path:   "/users/-1/posts/0f8fad5b-d9cb-469f-a165-70867728950e",
+			Status: 200,
+			Body:   "POST",
+			Variables: []*Variable{
+				{Name: "id", Value: "-1", RawValue: "-1", Constraint: "int"},
+				{Name: "post", Value: "0f8fad5b-d9cb-469f-a165-70867728950e", RawValue: "0f8fad5b-d9cb-469f-a165-70867728950e", Constraint: "uuid"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "/users/1/posts/0f8fad5b",
+			Status: 404,
+			Body:   NotFoundBody,
+		},
+		{
+			Method: "GET",
+			Path:   "/tags/go-lang",
+			Status: 200,
+			Body:   "TAG",
+			Variables: []*Variable{
+				{Name: "slug", Value: "go-lang", RawValue: "go-lang", Constraint: "[a-z-]+"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "/tags/Go",
+			Status: 404,
+			Body:   NotFoundBody,
+		},
+		{
+			Method: "GET",
+			Path:   "/files/a/b.txt",
+			Status: 200,
+			Body:   "FILE",
+			Variables: []*Variable{
+				{Name: "file", Value: "a/b.txt", RawValue: "a/b.txt", Constraint: ".+\\.txt"},
+			},
+		},
+		{
+			Method: "GET",
+			Path:   "/files/a/b.pdf",
+			Status: 404,
+			Body:   NotFoundBody,
+		},
+		{
+			Method: "GET",
+			Path:   "/dirs/a/b/c",
+			Status: 200,
+			Body:   "DIR",
+			Variables: []*Variable{
+				{Name: "dir", Value: "a/b/c", RawValue: "a/b/c", Constraint: "[a-z]+/./[a-z]+"},
+			},
+		},
+	}
+
+	testMux_ServeHTTP(t, m, tests...)
+}
+
+func TestMux_Handle_panicsWithUnequalConstraints(t *testing.T) {
+	m := New()
+	m.Handle("/users/:id{int}", TestHandler("USER"))
+
+	defer func() {
+		if _, ok := recover().(*ErrUnequalConstraints); !ok {
+			t.Errorf("recover() must be an *ErrUnequalConstraints")
+		}
+	}()
+	m.Handle("/users/:id{uuid}", TestHandler("USER"))
+}
+
+func TestMux_Swap_ServesNextRoutes(t *testing.T) {
+	m := New()
+	m.Handle("/old", TestHandler("OLD"))
+	old := m.SubRoute("/old/sub")
+
+	next := New()
+	next.Handle("/new", TestHandler("NEW"))
+
+	m.Swap(next)
+	old.Handle(TestHandler("OLD_SUB"))
+	next.Handle("/newer", TestHandler("NEWER"))
+	m.Handle("/after", TestHandler("AFTER"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/old", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/old/sub", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/new", Status: 200, Body: "NEW"},
+		&ServeHTTPTest{Method: "GET", Path: "/newer", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/after", Status: 200, Body: "AFTER"},
+	)
+	testMux_ServeHTTP(
+		t,
+		next,
+		&ServeHTTPTest{Method: "GET", Path: "/newer", Status: 200, Body: "NEWER"},
+		&ServeHTTPTest{Method: "GET", Path: "/after", Status: 404, Body: NotFoundBody},
+	)
+}
+
+func TestMux_RegistrationDoesNotModifyPublishedTrees(t *testing.T) {
+	m := New()
+	m.Handle("/users/:id", TestHandler("USER"), "GET")
+	m.Handle("/users/:id/keys", TestHandler("KEYS"))
+	m.Host("api.example.com").Handle(TestHandler("API"))
+	m.SubRoute("/users").WithMeta("tier", "gold")
+
+	before := m.routes().load()
+	want := before.dot().String()
+
+	m.Handle("/users/:id", TestHandler("USER"), "PUT")
+	m.Handle("/users/:id/keyring", TestHandler("KEYRING"))
+	m.Handle("/uploads/*path", TestHandler("UPLOADS"))
+	m.Host("api.example.com").SubRoute("/v1").Handle(TestHandler("V1"))
+	m.SubRoute("/users").Use(func(h http.Handler) http.Handler { return h }).WithMeta("tier", "silver")
+	m.Remove("/users/:id/keys")
+
+	if result := before.dot().String(); result != want {
+		t.Errorf("published tree =\n%s\nWANT\n%s", result, want)
+	}
+	attrs := findPattern(before.root, "/users/:id").attrs()
+	if len(attrs.chain) != 0 || attrs.allMeta["tier"] != "gold" {
+		t.Errorf("published chain, allMeta = %v, %v WANT none, gold", attrs.chain, attrs.allMeta)
+	}
+	if tier, _ := m.SubRoute("/users/:id/keyring").Meta("tier"); tier != "silver" {
+		t.Errorf("Meta(tier) = %v WANT silver", tier)
+	}
+}
+
+func TestMux_ServeHTTP_IsSafeWithConcurrentRegistration(t *testing.T) {
+	m := New()
+	m.Handle("/", TestHandler("ROOT"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			m.Handle(fmt.Sprintf("/route%v/:id", i), TestHandler("ROUTE")).Name(fmt.Sprint(i))
+			m.SubRoute("/route0").Use(func(h http.Handler) http.Handler { return h })
+			m.Use(func(h http.Handler) http.Handler { return h })
+
+			next := New()
+			next.Handle("/", TestHandler("ROOT"))
+			if i%10 == 0 {
+				m.Swap(next)
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		testMux_ServeHTTP(t, m, &ServeHTTPTest{Method: "GET", Path: "/", Status: 200, Body: "ROOT"})
+		m.URL(fmt.Sprint(i), &Variable{Name: "id", Value: "1"})
+	}
+	<-done
+}
+
+func TestMux_ServeHTTP_ServesAutoHeadAndOptions(t *testing.T) {
+	m := New()
+	m.AutoHead = true
+	m.AutoOptions = true
+
+	get := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Get", "true")
+		fmt.Fprint(w, "GET")
+	})
+	m.Handle("/get", get, "GET")
+	m.Handle("/get", TestHandler("POST"), "POST")
+	m.Handle("/override", get, "GET")
+	m.Handle("/override", TestHandler("HEAD"), "HEAD")
+	m.Handle("/override", TestHandler("OPTIONS"), "OPTIONS")
+	m.Handle("/post", TestHandler("POST"), "POST")
+	m.Handle("/all", TestHandler("ALL"))
+
+	tests := []*ServeHTTPTest{
+		{
+			Method: "HEAD",
+			Path:   "/get",
+			Status: 200,
+			Body:   "",
+			Header: http.Header{"X-Get": {"true"}},
+		},
+		{
+			Method: "OPTIONS",
+			Path:   "/get",
+			Status: 204,
+			Body:   "",
+			Header: http.Header{HeaderAllow: {"GET, HEAD, OPTIONS, POST"}},
+		},
+		{
+			Method: "DELETE",
+			Path:   "/get",
+			Status: 405,
+			Body:   "Method Not Allowed\n",
+			Header: http.Header{HeaderAllow: {"GET, HEAD, OPTIONS, POST"}},
+		},
+		{
+			Method: "HEAD",
+			Path:   "/override",
+			Status: 200,
+			Body:   "HEAD",
+		},
+		{
+			Method: "OPTIONS",
+			Path:   "/override",
+			Status: 200,
+			Body:   "OPTIONS",
+		},
+		{
+			Method: "HEAD",
+			Path:   "/post",
+			Status: 405,
+			Body:   "Method Not Allowed\n",
+			Header: http.Header{HeaderAllow: {"OPTIONS, POST"}},
+		},
+		{
+			Method: "OPTIONS",
+			Path:   "/all",
+			Status: 200,
+			Body:   "ALL",
+		},
+	}
+
+	testMux_ServeHTTP(t, m, tests...)
+}
+
+func TestRoute_DisableAutoHeadAndOptions(t *testing.T) {
+	m := New()
+	m.AutoHead = true
+	m.AutoOptions = true
+
+	get := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "GET")
+	})
+	m.Handle("/head", get, "GET").DisableAutoHead()
+	m.SubRoute("/options").DisableAutoOptions().Handle(get, "GET")
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "HEAD", Path: "/head", Status: 405, Body: "Method Not Allowed\n", Header: http.Header{HeaderAllow: {"GET, OPTIONS"}}},
+		&ServeHTTPTest{Method: "OPTIONS", Path: "/head", Status: 204, Header: http.Header{HeaderAllow: {"GET, OPTIONS"}}},
+		&ServeHTTPTest{Method: "HEAD", Path: "/options", Status: 200, Body: ""},
+		&ServeHTTPTest{Method: "OPTIONS", Path: "/options", Status: 405, Body: "Method Not Allowed\n", Header: http.Header{HeaderAllow: {"GET, HEAD"}}},
+	)
+}
+
+func TestMux_ServeHTTP_RedirectsToCanonicalPaths(t *testing.T) {
+	m := New()
+	m.AllowTrailingSlashes = true
+	m.RedirectCleanPath = true
+	m.RedirectTrailingSlash = true
+
+	m.Handle("/foo", TestHandler("FOO"))
+	m.Handle("/bar/", TestHandler("BAR"))
+	m.Handle("/users/:id", TestHandler("USER"), "POST")
+
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{"GET", "/foo", 200, ""},
+		{"GET", "/foo/", 301, "/foo"},
+		{"GET", "/foo/?a=b&c", 301, "/foo?a=b&c"},
+		{"HEAD", "/bar", 301, "/bar/"},
+		{"POST", "/bar", 308, "/bar/"},
+		{"GET", "/bar/", 200, ""},
+		{"GET", "/a/../foo", 301, "/foo"},
+		{"PUT", "/bar/./../foo?x=1", 308, "/foo?x=1"},
+		{"GET", "/bar//", 301, "/bar/"},
+		{"POST", "/users/1/", 308, "/users/1"},
+		{"GET", "/users/1/", 404, ""},
+		{"OPTIONS", "*", 404, ""},
+		{"GET", "/baz/", 404, ""},
+		{"GET", "/", 404, ""},
+	}
+
+	for i, test := range tests {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%v: w.Code = %v WANT %v", i, w.Code, test.status)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%v: Location = %q WANT %q", i, location, test.location)
+		}
+	}
+}
+
 func TestMux_ServeHTTP_ServesUnhandledRootWithANotFound(t *testing.T) {
 	m := New()
 
@@ -202,9 +610,480 @@ func testMux_ServeHTTP(t *testing.T, m *Mux, tests ...*ServeHTTPTest) {
 		}
 
 		for key, values := range test.Header {
-			if actual := w.Header().Get(key); !reflect.DeepEqual(actual, values) {
+			if actual := w.Header()[key]; !reflect.DeepEqual(actual, values) {
 				t.Errorf("%v: desired Header %v = %v WANT %v", i, key, actual, values)
 			}
 		}
 	}
 }
+
+func TestMux_ServeHTTP_StoresVariablesUnderTypedKey(t *testing.T) {
+	type stringKey string
+
+	m := New()
+	var values []interface{}
+	m.HandleFunc("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context()
+		values = []interface{}{
+			VariableValue(c, "id"),
+			c.Value(VarName("id")),
+			c.Value(VarName("other")),
+			c.Value("id"),
+			c.Value(stringKey("id")),
+		}
+	})
+
+	r, _ := http.NewRequest("GET", "/users/1", nil)
+	r = r.WithContext(context.WithValue(r.Context(), "id", "parent"))
+	m.ServeHTTP(httptest.NewRecorder(), r)
+
+	want := []interface{}{"1", "1", nil, "parent", nil}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v WANT %v", values, want)
+	}
+}
+
+func TestMux_ServeHTTP_VariablesRemainInTheContextAfterServing(t *testing.T) {
+	m := New()
+	m.Handle("/users/:id/posts/:post", TestHandler("POST"))
+
+	first := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ := http.NewRequest("GET", "/users/1/posts/2", nil)
+	m.ServeHTTP(first, r)
+
+	second := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ = http.NewRequest("GET", "/users/3/posts/4", nil)
+	m.ServeHTTP(second, r)
+
+	values := []string{VariableValue(first.Context, "id"), VariableValue(first.Context, "post")}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v WANT %v", values, want)
+	}
+	if route, ok := RouteFrom(first.Context); !ok || route.Pattern != "/users/:id/posts/:post" {
+		t.Errorf("RouteFrom() = %v, %v WANT pattern %q, true", route, ok, "/users/:id/posts/:post")
+	}
+}
+
+func TestMux_ServeHTTP_UseEscapedPathMatchesEscapedSlashesInVariables(t *testing.T) {
+	m := New()
+	m.UseEscapedPath = true
+
+	m.Handle("/files/:name", TestHandler("FILE"))
+	m.Handle("/files/:name/meta", TestHandler("META"))
+	m.Handle("/slugs/:slug{[a-z/]+}", TestHandler("SLUG"))
+	m.Handle("/static/*rest", TestHandler("STATIC"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/a%2Fb", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "a/b", RawValue: "a%2Fb"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/a%2Fb/meta", Status: 200, Body: "META",
+			Variables: []*Variable{{Name: "name", Value: "a/b", RawValue: "a%2Fb"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/plain", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "plain", RawValue: "plain"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/slugs/a%2Fb", Status: 200, Body: "SLUG",
+			Variables: []*Variable{{Name: "slug", Value: "a/b", RawValue: "a%2Fb", Constraint: "[a-z/]+"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/static/a%20b/c%2Fd", Status: 200, Body: "STATIC",
+			Variables: []*Variable{{Name: "rest", Value: "a b/c/d", RawValue: "a%20b/c%2Fd"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/files/a/b", Status: 404, Body: NotFoundBody},
+	)
+
+	m.UseEscapedPath = false
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/files/a%2Fb", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/a%20b", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "a b", RawValue: "a%20b"}},
+		},
+	)
+}
+
+func TestMux_ServeHTTP_UseEscapedPathRedirectsToEscapedPaths(t *testing.T) {
+	m := New()
+	m.UseEscapedPath = true
+	m.RedirectCleanPath = true
+	m.RedirectTrailingSlash = true
+
+	m.Handle("/files/:name", TestHandler("FILE"))
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/files//a%2Fb", "/files/a%2Fb"},
+		{"/files/a%2Fb/", "/files/a%2Fb"},
+		{"/x/../files/a%20b?q=1", "/files/a%20b?q=1"},
+	}
+
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", test.path, nil)
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("%v: w.Code = %v WANT %v", i, w.Code, http.StatusMovedPermanently)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%v: Location = %q WANT %q", i, location, test.location)
+		}
+	}
+}
+
+func TestMux_ServeHTTP_CaseInsensitiveMatchesStaticPartsIgnoringCase(t *testing.T) {
+	m := New()
+	m.CaseInsensitive = true
+
+	m.Handle("/users", TestHandler("USERS"))
+	m.Handle("/members/:id/Posts", TestHandler("POSTS"))
+	m.Handle("/Users/exact", TestHandler("EXACT_UPPER"))
+	m.Handle("/users/exact", TestHandler("EXACT_LOWER"))
+	m.Handle("/files/*path", TestHandler("FILES"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/users", Status: 200, Body: "USERS"},
+		&ServeHTTPTest{Method: "GET", Path: "/USERS", Status: 200, Body: "USERS"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/mEmBeRs/AbC/posts", Status: 200, Body: "POSTS",
+			Variables: []*Variable{{Name: "id", Value: "AbC", RawValue: "AbC"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/Users/exact", Status: 200, Body: "EXACT_UPPER"},
+		&ServeHTTPTest{Method: "GET", Path: "/users/exact", Status: 200, Body: "EXACT_LOWER"},
+		&ServeHTTPTest{Method: "GET", Path: "/USERS/EXACT", Status: 200, Body: "EXACT_UPPER"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/FILES/A/b", Status: 200, Body: "FILES",
+			Variables: []*Variable{{Name: "path", Value: "A/b", RawValue: "A/b"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/userz", Status: 404, Body: NotFoundBody},
+	)
+
+	m.CaseInsensitive = false
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/USERS", Status: 404, Body: NotFoundBody},
+	)
+}
+
+func TestMux_ServeHTTP_RedirectCanonicalCaseRedirectsToRegisteredCase(t *testing.T) {
+	m := New()
+	m.CaseInsensitive = true
+	m.RedirectCanonicalCase = true
+
+	m.Handle("/Users/:id/posts", TestHandler("POSTS"), "GET")
+	m.Handle("/Users", TestHandler("USERS"))
+
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{"GET", "/Users/AbC/posts", 200, ""},
+		{"GET", "/users/AbC/POSTS?q=1", 301, "/Users/AbC/posts?q=1"},
+		{"POST", "/USERS/x/Posts", 308, "/Users/x/posts"},
+		{"GET", "/users", 301, "/Users"},
+		{"GET", "/people", 404, ""},
+	}
+
+	for i, test := range tests {
+		w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+		r, _ := http.NewRequest(test.method, test.path, nil)
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%v: w.Code = %v WANT %v", i, w.Code, test.status)
+		}
+		if location := w.Header().Get("Location"); location != test.location {
+			t.Errorf("%v: Location = %q WANT %q", i, location, test.location)
+		}
+	}
+}
+
+func TestMux_Handle_RegistersEveryExpansionOfOptionalParts(t *testing.T) {
+	m := New()
+
+	var calls []string
+	docs := m.Handle("/docs[/:page[/:section]]", TestHandler("DOCS"), "GET")
+	docs.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, r.URL.Path)
+			next.ServeHTTP(w, r)
+		})
+	})
+	m.SubRoute("/api[/v1]").SubRoute("/users").Handle(TestHandler("USERS"))
+
+	if pattern := docs.Pattern(); pattern != "/docs[/:page[/:section]]" {
+		t.Errorf("docs.Pattern() = %q", pattern)
+	}
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/docs", Status: 200, Body: "DOCS"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/docs/intro", Status: 200, Body: "DOCS",
+			Variables: []*Variable{{Name: "page", Value: "intro", RawValue: "intro"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/docs/intro/a", Status: 200, Body: "DOCS",
+			Variables: []*Variable{{Name: "page", Value: "intro", RawValue: "intro"}, {Name: "section", Value: "a", RawValue: "a"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/api/users", Status: 200, Body: "USERS"},
+		&ServeHTTPTest{Method: "GET", Path: "/api/v1/users", Status: 200, Body: "USERS"},
+		&ServeHTTPTest{Method: "GET", Path: "/docs/intro/a/b", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "POST", Path: "/docs/intro", Status: 405, Body: "Method Not Allowed\n"},
+	)
+
+	if want := []string{"/docs", "/docs/intro", "/docs/intro/a", "/docs/intro"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("middleware calls = %v WANT %v", calls, want)
+	}
+
+	r, _ := http.NewRequest("GET", "/docs", nil)
+	m.Handle("/short[/:page]", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := VariableFromOk(r.Context(), "page"); ok {
+			t.Errorf("page is present WANT absent")
+		}
+	}))
+	r.URL.Path = "/short"
+	m.ServeHTTP(httptest.NewRecorder(), r)
+
+	if !m.Remove("/docs[/:page[/:section]]") {
+		t.Errorf("Remove() = false WANT true")
+	}
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/docs", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/docs/intro", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/api/v1/users", Status: 200, Body: "USERS"},
+	)
+
+	if _, err := m.TryHandle("/bad[/:page", TestHandler("BAD")); err == nil {
+		t.Errorf("TryHandle() error = nil WANT error")
+	}
+}
+
+func TestMux_ServeHTTP_MatchesMultipleVariablesInOneSegment(t *testing.T) {
+	m := New()
+
+	m.Handle("/files/:name.:ext", TestHandler("FILE"))
+	m.Handle("/files/:name/meta", TestHandler("META"))
+	m.Handle("/v:major.:minor/", TestHandler("VERSION"))
+	m.Handle("/numbers/:id{int}.json", TestHandler("JSON"))
+	m.Handle("/numbers/:id{int}", TestHandler("NUMBER"))
+	m.Handle("/types/:name.:ext{[a-z]+}", TestHandler("TYPE"))
+	m.Handle("/range/:from-:to", TestHandler("RANGE"))
+	m.Handle("/users/:user-id", TestHandler("USER"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/report.pdf", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "report", RawValue: "report"}, {Name: "ext", Value: "pdf", RawValue: "pdf"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/archive.tar.gz", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "archive", RawValue: "archive"}, {Name: "ext", Value: "tar.gz", RawValue: "tar.gz"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/report.pdf/meta", Status: 200, Body: "META",
+			Variables: []*Variable{{Name: "name", Value: "report.pdf", RawValue: "report.pdf"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/files/report", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/files/.pdf", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/v1.12/", Status: 200, Body: "VERSION",
+			Variables: []*Variable{{Name: "major", Value: "1", RawValue: "1"}, {Name: "minor", Value: "12", RawValue: "12"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/numbers/12.json", Status: 200, Body: "JSON",
+			Variables: []*Variable{{Name: "id", Value: "12", RawValue: "12", Constraint: "int"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/numbers/12", Status: 200, Body: "NUMBER",
+			Variables: []*Variable{{Name: "id", Value: "12", RawValue: "12", Constraint: "int"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/numbers/ab.json", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/types/a.b.c", Status: 200, Body: "TYPE",
+			Variables: []*Variable{{Name: "name", Value: "a.b", RawValue: "a.b"}, {Name: "ext", Value: "c", RawValue: "c", Constraint: "[a-z]+"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/range/1-10", Status: 200, Body: "RANGE",
+			Variables: []*Variable{{Name: "from", Value: "1", RawValue: "1"}, {Name: "to", Value: "10", RawValue: "10"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/1", Status: 200, Body: "USER",
+			Variables: []*Variable{{Name: "user-id", Value: "1", RawValue: "1"}},
+		},
+	)
+}
+
+func TestMux_TryHandle_ReturnsErrorsForAmbiguousSegments(t *testing.T) {
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{"/:a:b", &ErrConsecutiveVars{Variable1: "a", Variable2: "b"}},
+		{"/:a{int}:b", &ErrConsecutiveVars{Variable1: "a", Variable2: "b"}},
+		{"/:a*b", &ErrConsecutiveVars{Variable1: "a", Variable2: "b"}},
+	}
+
+	for i, test := range tests {
+		_, err := New().TryHandle(test.path, TestHandler(""))
+		errIR, ok := err.(*ErrInvalidRoute)
+		if !ok || !reflect.DeepEqual(errIR.Err, test.err) {
+			t.Errorf("%v: TryHandle(%q) error = %v WANT %v", i, test.path, err, test.err)
+		}
+	}
+}
+
+func TestMux_ServeHTTP_PrefersStaticsOverSegmentVariables(t *testing.T) {
+	m := New()
+
+	m.Handle("/users/", TestHandler("USERS"))
+	m.Handle("/users/me", TestHandler("ME"))
+	m.Handle("/users/:id", TestHandler("USER"))
+	m.Handle("/users/:id/posts", TestHandler("POSTS"))
+	m.Handle("/users/me/settings", TestHandler("SETTINGS"))
+	m.Handle("/users/me/*rest", TestHandler("ME_REST"))
+	m.Handle("/files/:name", TestHandler("FILE"))
+	m.Handle("/files/index.html", TestHandler("INDEX"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/users/", Status: 200, Body: "USERS"},
+		&ServeHTTPTest{Method: "GET", Path: "/users/me", Status: 200, Body: "ME"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/mee", Status: 200, Body: "USER",
+			Variables: []*Variable{{Name: "id", Value: "mee", RawValue: "mee"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/m", Status: 200, Body: "USER",
+			Variables: []*Variable{{Name: "id", Value: "m", RawValue: "m"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/users/me/settings", Status: 200, Body: "SETTINGS"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/me/posts", Status: 200, Body: "ME_REST",
+			Variables: []*Variable{{Name: "rest", Value: "posts", RawValue: "posts"}},
+		},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/1/posts", Status: 200, Body: "POSTS",
+			Variables: []*Variable{{Name: "id", Value: "1", RawValue: "1"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/files/index.html", Status: 200, Body: "INDEX"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/files/index.htm", Status: 200, Body: "FILE",
+			Variables: []*Variable{{Name: "name", Value: "index.htm", RawValue: "index.htm"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/users/1/other", Status: 404, Body: NotFoundBody},
+	)
+}
+
+func TestMux_ServeHTTP_BacktracksFromStaticsToSegmentVariables(t *testing.T) {
+	m := New()
+
+	m.Handle("/users/me/settings", TestHandler("SETTINGS"))
+	m.Handle("/users/:id/posts", TestHandler("POSTS"))
+
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/users/me/settings", Status: 200, Body: "SETTINGS"},
+		&ServeHTTPTest{
+			Method: "GET", Path: "/users/me/posts", Status: 200, Body: "POSTS",
+			Variables: []*Variable{{Name: "id", Value: "me", RawValue: "me"}},
+		},
+		&ServeHTTPTest{Method: "GET", Path: "/users/me", Status: 404, Body: NotFoundBody},
+	)
+}
+
+func TestRouteFrom_ReturnsTheMatchedRouteAndMeta(t *testing.T) {
+	m := New()
+
+	var routes []MatchedRoute
+	record := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, ok := RouteFrom(r.Context())
+			if !ok {
+				t.Errorf("%v: RouteFrom() ok = false WANT true", r.URL.Path)
+			}
+			routes = append(routes, route)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	users := m.SubRoute("/users").Use(record).WithMeta("tier", "gold")
+	users.SubRoute("/:id").WithMeta("scope", "users:read").WithMeta("team", "identity").Handle(TestHandler("USER"))
+	users.Handle(TestHandler("USERS")).WithMeta("scope", "users:list")
+	users.SubRoute("/:id").WithMeta("scope", "users:write")
+	m.Host("{tenant}.example.com").SubRoute("/docs[/:page]").WithMeta("public", true).Handle(TestHandler("DOCS"))
+
+	for _, path := range []string{"/users/1", "/users", "http://acme.example.com/docs/intro"} {
+		r, _ := http.NewRequest("GET", path, nil)
+		m.ServeHTTP(&TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}, r)
+	}
+
+	want := []MatchedRoute{
+		{Pattern: "/users/:id", Meta: map[string]interface{}{"scope": "users:write", "team": "identity", "tier": "gold"}},
+		{Pattern: "/users", Meta: map[string]interface{}{"scope": "users:list", "tier": "gold"}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("routes = %v WANT %v", routes, want)
+	}
+
+	w := &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ := http.NewRequest("GET", "http://acme.example.com/docs/intro", nil)
+	m.ServeHTTP(w, r)
+	route, ok := RouteFrom(w.Context)
+	wantRoute := MatchedRoute{
+		Host:    "{tenant}.example.com",
+		Pattern: "/docs/:page",
+		Meta:    map[string]interface{}{"public": true},
+	}
+	if !ok || !reflect.DeepEqual(route, wantRoute) {
+		t.Errorf("RouteFrom() = %v, %v WANT %v, true", route, ok, wantRoute)
+	}
+
+	if value, ok := users.Meta("scope"); value != "users:list" || !ok {
+		t.Errorf("users.Meta(scope) = %v, %v WANT users:list, true", value, ok)
+	}
+
+	m.Handle("/static", TestHandler("STATIC"))
+	w = &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	r, _ = http.NewRequest("GET", "/static", nil)
+	m.ServeHTTP(w, r)
+	if route, ok := RouteFrom(w.Context); ok {
+		t.Errorf("RouteFrom() = %v, %v WANT false without Variables, Meta, or middleware", route, ok)
+	}
+	m.SubRoute("/static").WithMeta("public", true)
+	w = &TestResponseWriter{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(w, r)
+	if route, ok := RouteFrom(w.Context); !ok || route.Pattern != "/static" {
+		t.Errorf("RouteFrom() = %v, %v WANT pattern /static, true", route, ok)
+	}
+
+	m.Handle("/usersx", TestHandler("USERSX"))
+	if value, ok := m.SubRoute("/usersx").Meta("tier"); ok {
+		t.Errorf("usersx.Meta(tier) = %v, true WANT nil, false", value)
+	}
+}
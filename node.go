@@ -3,6 +3,7 @@ package httpmux
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	muxpath "github.com/gogolfing/httpmux/path"
@@ -10,16 +11,61 @@ import (
 
 var errInvalidState = fmt.Errorf("")
 
+//node is a single location in the routing tree.
+//
+//The append methods return the node at the appended location. If an error is
+//returned, then the returned node, if not nil, is the existing node that the
+//append conflicted with.
 type node interface {
 	appendStatic(static string) (node, error)
-	appendSegmentVar(name VarName) (node, error)
-	appendEndVar(name VarName) (node, error)
+	appendSegmentVar(name VarName, c *constraint) (node, error)
+	appendEndVar(name VarName, c *constraint) (node, error)
 
-	find(path string, m foundMatcher) (found node, vars []*Variable)
+	find(path string, m foundMatcher, mt *match) (found node)
 
 	put(handler http.Handler, methods ...string)
+	remove(methods ...string)
 	get(cleanedMethod string) (http.Handler, error)
 	isRegistered() bool
+	handlers() *methodHandler
+
+	attrs() *attributes
+}
+
+//attributes are the properties of a node other than its handlers and
+//children.
+type attributes struct {
+	//pattern is the pattern of the Route that the node was registered with.
+	pattern string
+
+	middleware []Middleware
+
+	//chain is the middleware that wraps the handlers of the node: the
+	//middleware of the node and of the ancestors that it is beneath, see
+	//patternBeneath. It is set when the tree is published.
+	chain []Middleware
+
+	//meta is replaced instead of modified when metadata is added so that
+	//copies of the node may share it.
+	meta map[string]interface{}
+
+	//allMeta is meta merged over the meta of the ancestors that the node is
+	//beneath. It is set when the tree is published and must not be modified.
+	allMeta map[string]interface{}
+
+	//gen is the generation of the routeTree that owns the node. Only nodes
+	//owned by the tree being updated may be modified, see routeTree.clone.
+	gen uint64
+}
+
+func (a *attributes) attrs() *attributes {
+	return a
+}
+
+//isEmpty returns whether a has nothing that would be lost if its node were
+//removed from the tree.
+func (a *attributes) isEmpty() bool {
+	return len(a.middleware) == 0 && len(a.meta) == 0
 }
 
 type staticNode struct {
@@ -30,22 +76,21 @@ type staticNode struct {
 	endVarChild     *endVarNode
 
 	methodHandler
+	attributes
 }
 
 func (n *staticNode) appendStatic(static string) (node, error) {
 	if len(static) == 0 {
 		return n, nil
 	}
-	if n.segmentVarChild != nil {
-		return nil, ErrOverlapStaticVar(n.segmentVarChild.name)
-	}
 	index := n.indexOfCommonPrefixChild(static)
 	if index < 0 { //child not found. needs to be inserted at ^index.
-		newChild := &staticNode{value: static}
+		newChild := &staticNode{value: static, attributes: attributes{gen: n.gen}}
 		n.insertStaticChildAtIndex(newChild, ^index)
 		return newChild, nil
 	}
 	//now we know which child to insert on
+	n.staticChildren[index] = ownStatic(n.staticChildren[index], n.gen)
 	return newInsertStatic(&n.staticChildren[index], n.staticChildren[index], static)
 }
 
@@ -61,6 +106,7 @@ func newInsertStatic(parent **staticNode, toSplit *staticNode, static string) (n
 	newNode := &staticNode{
 		value:          prefix,
 		staticChildren: []*staticNode{toSplit},
+		attributes:     attributes{gen: toSplit.gen},
 	}
 	*parent = newNode
 	toSplit.value = toSplit.value[len(prefix):]
@@ -80,80 +126,128 @@ func (n *staticNode) insertStaticChildAtIndex(child *staticNode, index int) {
 	n.staticChildren = append(n.staticChildren, after...)
 }
 
-func (n *staticNode) appendSegmentVar(name VarName) (node, error) {
-	if len(n.staticChildren) > 0 { //static case
-		return nil, ErrOverlapStaticVar(name)
-	}
+func (n *staticNode) appendSegmentVar(name VarName, c *constraint) (node, error) {
 	if n.segmentVarChild == nil && n.endVarChild == nil { //empty case
-		n.segmentVarChild = &segmentVarNode{name: name}
+		n.segmentVarChild = &segmentVarNode{name: name, constraint: c, attributes: attributes{gen: n.gen}}
 		return n.segmentVarChild, nil
 	}
 	if n.segmentVarChild != nil {
 		if n.segmentVarChild.name != name { //unequal names
-			return nil, &ErrUnequalVars{Variable1: n.segmentVarChild.name, Variable2: name}
+			return n.segmentVarChild, &ErrUnequalVars{Variable1: n.segmentVarChild.name, Variable2: name}
+		}
+		if !n.segmentVarChild.constraint.equals(c) { //unequal constraints
+			return n.segmentVarChild, &ErrUnequalConstraints{name, n.segmentVarChild.constraint.String(), c.String()}
 		}
+		n.segmentVarChild = ownSegmentVar(n.segmentVarChild, n.gen)
 		return n.segmentVarChild, nil //otherwise names are equal so return the child
 	}
 	//now we must have an end variable. this is always an error.
-	return nil, &ErrUnequalVars{n.endVarChild.name, name}
+	return n.endVarChild, &ErrUnequalVars{n.endVarChild.name, name}
 }
 
-func (n *staticNode) appendEndVar(name VarName) (node, error) {
-	if len(n.staticChildren) > 0 { //static case
-		n.endVarChild = &endVarNode{name: name}
-		return n.endVarChild, nil
+func (n *staticNode) appendEndVar(name VarName, c *constraint) (node, error) {
+	if n.segmentVarChild != nil {
+		return n.segmentVarChild, &ErrUnequalVars{Variable1: n.segmentVarChild.name, Variable2: name}
 	}
-	if n.segmentVarChild == nil && n.endVarChild == nil { //empty case
-		n.endVarChild = &endVarNode{name: name}
+	if n.endVarChild == nil { //empty or static case
+		n.endVarChild = &endVarNode{name: name, constraint: c, attributes: attributes{gen: n.gen}}
 		return n.endVarChild, nil
 	}
-	if n.segmentVarChild != nil {
-		return nil, &ErrUnequalVars{Variable1: n.segmentVarChild.name, Variable2: name}
-	}
 	if n.endVarChild.name != name { //unequal names
-		return nil, &ErrUnequalVars{Variable1: n.endVarChild.name, Variable2: name}
+		return n.endVarChild, &ErrUnequalVars{Variable1: n.endVarChild.name, Variable2: name}
+	}
+	if !n.endVarChild.constraint.equals(c) { //unequal constraints
+		return n.endVarChild, &ErrUnequalConstraints{name, n.endVarChild.constraint.String(), c.String()}
 	}
 	//otherwise names are equal so return the child
+	n.endVarChild = ownEndVar(n.endVarChild, n.gen)
 	return n.endVarChild, nil
 }
 
-func (n *staticNode) find(path string, m foundMatcher) (node, []*Variable) {
-	if prefix := muxpath.CommonPrefix(path, n.value); len(prefix) != len(n.value) {
-		return nil, nil
+func (n *staticNode) find(path string, m foundMatcher, mt *match) node {
+	mt.traceNode(n, path)
+	folded := false
+	if !strings.HasPrefix(path, n.value) {
+		if !mt.ignoreCase || !muxpath.HasPrefixFold(path, n.value) {
+			return mt.traceEnd(nil, TraceNoMatch)
+		}
+		folded = true
 	}
 
-	remaining := path[len(n.value):]
-
-	//if true then child must be segment variable
-	if n.segmentVarChild != nil {
-		return n.maybeFindSegmentVarChild(remaining, m)
+	mark := mt.mark()
+	if folded {
+		mt.fold(path, n.value)
+	}
+	found := n.findRemaining(path[len(n.value):], m, mt)
+	if found == nil {
+		mt.reset(mark)
 	}
-	//now we know either static or end variable child
+	if mt.trace != nil {
+		mt.traceEnd(nil, foundResult(n, found))
+	}
+	return found
+}
 
-	found, vars := n.findStaticChildDescendant(remaining, m)
-	if found != nil {
-		return found, vars
+//findRemaining searches n's children in priority order: static children, then
+//n itself, then the segment variable child, and then the end variable child.
+//A later alternative is only searched if the earlier ones do not find
+//remaining.
+func (n *staticNode) findRemaining(remaining string, m foundMatcher, mt *match) node {
+	if found := n.findStaticChildDescendant(remaining, m, mt); found != nil {
+		return found
 	}
 
 	if m.matches(n, remaining) {
-		return n, nil
+		return n
+	}
+
+	if n.segmentVarChild != nil {
+		if found := n.maybeFindSegmentVarChild(remaining, m, mt); found != nil {
+			return found
+		}
 	}
 
 	if n.endVarChild != nil {
-		return n.endVarChild.find(remaining, m)
+		return n.endVarChild.find(remaining, m, mt)
 	}
 
-	return nil, nil
+	return nil
 }
 
-func (n *staticNode) findStaticChildDescendant(path string, m foundMatcher) (node, []*Variable) {
+func (n *staticNode) findStaticChildDescendant(path string, m foundMatcher, mt *match) node {
 	index := n.indexOfCommonPrefixChild(path)
 
+	if index >= 0 {
+		if found := n.staticChildren[index].find(path, m, mt); found != nil {
+			return found
+		}
+	}
+
+	if !mt.ignoreCase || len(path) == 0 {
+		return nil
+	}
+
+	//children start with unique bytes, so there is at most one other child that
+	//path may match ignoring case.
+	toggled := muxpath.ToggleCase(path[0])
+	if toggled == path[0] {
+		return nil
+	}
+	index = n.indexOfChildStartingWith(toggled)
 	if index < 0 {
-		return nil, nil
+		return nil
 	}
+	return n.staticChildren[index].find(path, m, mt)
+}
 
-	return n.staticChildren[index].find(path, m)
+func (n *staticNode) indexOfChildStartingWith(b byte) int {
+	index := sort.Search(len(n.staticChildren), func(i int) bool {
+		return n.staticChildren[i].value[0] >= b
+	})
+	if index < len(n.staticChildren) && n.staticChildren[index].value[0] == b {
+		return index
+	}
+	return -1
 }
 
 func (n *staticNode) indexOfCommonPrefixChild(static string) int {
@@ -173,111 +267,182 @@ func (n *staticNode) indexOfCommonPrefixChild(static string) int {
 }
 
 //n.segmentVarChild must not be nil.
-func (n *staticNode) maybeFindSegmentVarChild(path string, m foundMatcher) (node, []*Variable) {
+func (n *staticNode) maybeFindSegmentVarChild(path string, m foundMatcher, mt *match) node {
 	if len(path) == 0 && strings.HasSuffix(n.value, muxpath.Slash) {
-		return nil, nil
+		mt.traceNode(n.segmentVarChild, path)
+		return mt.traceEnd(nil, TraceSkippedEmptyValue)
 	}
-	return n.segmentVarChild.find(path, m)
+	return n.segmentVarChild.find(path, m, mt)
 }
 
 //n.endVarChild must not be nil.
-func (n *staticNode) maybeFindEndVarChild(path string, m foundMatcher) (node, []*Variable) {
+func (n *staticNode) maybeFindEndVarChild(path string, m foundMatcher, mt *match) node {
 	if len(path) == 0 {
-		return nil, nil
+		return nil
 	}
-	return n.endVarChild.find(path, m)
+	return n.endVarChild.find(path, m, mt)
 }
 
 type segmentVarNode struct {
-	name VarName
+	name       VarName
+	constraint *constraint
 
 	staticChild *staticNode
 
 	methodHandler
+	attributes
 }
 
 func (n *segmentVarNode) appendStatic(static string) (node, error) {
 	if n.staticChild == nil {
 		n.staticChild = &staticNode{
-			value: static,
+			value:      static,
+			attributes: attributes{gen: n.gen},
 		}
 		return n.staticChild, nil
 	}
 
-	// return n.staticChild.insertStatic(n.staticChild, static)
+	//static children without a common prefix, e.g. "/posts" and ".json", are
+	//children of an empty branch node.
+	if len(n.staticChild.value) > 0 && muxpath.CommonPrefixLen(n.staticChild.value, static) == 0 {
+		n.staticChild = &staticNode{staticChildren: []*staticNode{n.staticChild}, attributes: attributes{gen: n.gen}}
+	}
+	n.staticChild = ownStatic(n.staticChild, n.gen)
+	if len(n.staticChild.value) == 0 {
+		return n.staticChild.appendStatic(static)
+	}
+
 	return newInsertStatic(&n.staticChild, n.staticChild, static)
 }
 
-func (n *segmentVarNode) appendSegmentVar(name VarName) (node, error) {
+func (n *segmentVarNode) appendSegmentVar(name VarName, _ *constraint) (node, error) {
 	return nil, &ErrConsecutiveVars{
 		Variable1: n.name,
 		Variable2: name,
 	}
 }
 
-func (n *segmentVarNode) appendEndVar(name VarName) (node, error) {
+func (n *segmentVarNode) appendEndVar(name VarName, _ *constraint) (node, error) {
 	return nil, &ErrConsecutiveVars{
 		Variable1: n.name,
 		Variable2: name,
 	}
 }
 
-func (n *segmentVarNode) find(path string, m foundMatcher) (node, []*Variable) {
-	index := strings.IndexRune(path, muxpath.SlashRune)
-	if index < 0 {
-		index = len(path)
+func (n *segmentVarNode) find(path string, m foundMatcher, mt *match) node {
+	end := strings.IndexRune(path, muxpath.SlashRune)
+	if end < 0 {
+		end = len(path)
 	}
 
-	vars := []*Variable{
-		&Variable{
-			Name:  VarName(n.name),
-			Value: path[:index],
-		},
+	//if a static child continues the segment, then the value may end before
+	//the end of the segment. the shortest non-empty value is tried first.
+	if n.staticChild != nil && n.staticChild.continuesSegment() {
+		for i := 1; i < end; i++ {
+			if !n.staticChild.mayStartWith(path[i], mt.ignoreCase) {
+				continue
+			}
+			if found := n.findEndingAt(path, i, m, mt); found != nil {
+				return found
+			}
+		}
 	}
 
-	remaining := path[index:]
+	return n.findEndingAt(path, end, m, mt)
+}
 
-	if n.staticChild != nil {
-		var childVars []*Variable = nil
-		found, childVars := n.staticChild.find(remaining, m)
+//findEndingAt finds path with n's value ending at index end.
+func (n *segmentVarNode) findEndingAt(path string, end int, m foundMatcher, mt *match) node {
+	mt.traceNode(n, path)
+	mt.traceCapture(path[:end])
+	mark := mt.mark()
+	if !mt.capture(n.name, path[:end], n.constraint, false) {
+		mt.reset(mark)
+		return mt.traceEnd(nil, TraceConstraintNotSatisfied)
+	}
+
+	remaining := path[end:]
 
-		if found != nil {
-			return found, append(vars, childVars...)
+	if n.staticChild != nil {
+		if found := n.staticChild.find(remaining, m, mt); found != nil {
+			return mt.traceEnd(found, TraceFoundBeneath)
 		}
 	}
 
 	if m.matches(n, remaining) {
-		return n, vars
+		return mt.traceEnd(n, TraceFound)
 	}
 
-	return nil, nil
+	mt.reset(mark)
+	return mt.traceEnd(nil, TraceNotFoundBeneath)
+}
+
+//continuesSegment returns whether n, or one of its children if n is an empty
+//branch node, does not start with a slash.
+func (n *staticNode) continuesSegment() bool {
+	if len(n.value) > 0 {
+		return n.value[0] != muxpath.SlashRune
+	}
+	for _, child := range n.staticChildren {
+		if child.value[0] != muxpath.SlashRune {
+			return true
+		}
+	}
+	return false
+}
+
+//mayStartWith returns whether n, or one of its children if n is an empty branch
+//node, starts with b.
+func (n *staticNode) mayStartWith(b byte, ignoreCase bool) bool {
+	if len(n.value) > 0 {
+		return n.value[0] == b || (ignoreCase && n.value[0] == muxpath.ToggleCase(b))
+	}
+	if n.indexOfChildStartingWith(b) >= 0 {
+		return true
+	}
+	return ignoreCase && n.indexOfChildStartingWith(muxpath.ToggleCase(b)) >= 0
 }
 
 type endVarNode struct {
-	name VarName
+	name       VarName
+	constraint *constraint
 
 	methodHandler
+	attributes
 }
 
 func (n *endVarNode) appendStatic(static string) (node, error) {
 	return nil, errInvalidState
 }
 
-func (n *endVarNode) appendSegmentVar(name VarName) (node, error) {
+func (n *endVarNode) appendSegmentVar(name VarName, _ *constraint) (node, error) {
 	return nil, errInvalidState
 }
 
-func (n *endVarNode) appendEndVar(name VarName) (node, error) {
+func (n *endVarNode) appendEndVar(name VarName, _ *constraint) (node, error) {
 	return nil, errInvalidState
 }
 
-func (n *endVarNode) find(path string, _ foundMatcher) (node, []*Variable) {
-	return n, []*Variable{
-		&Variable{
-			Name:  VarName(n.name),
-			Value: path,
-		},
+func (n *endVarNode) find(path string, _ foundMatcher, mt *match) node {
+	mt.traceNode(n, path)
+	mt.traceCapture(path)
+	mark := mt.mark()
+	if !mt.capture(n.name, path, n.constraint, true) {
+		mt.reset(mark)
+		return mt.traceEnd(nil, TraceConstraintNotSatisfied)
+	}
+	return mt.traceEnd(n, TraceFound)
+}
+
+//foundResult returns the trace result of searching n when found is found.
+func foundResult(n, found node) string {
+	switch found {
+	case nil:
+		return TraceNotFoundBeneath
+	case n:
+		return TraceFound
 	}
+	return TraceFoundBeneath
 }
 
 type foundMatcher interface {
@@ -0,0 +1,5 @@
+//go:build !race
+
+package httpmux
+
+const raceEnabled = false
@@ -0,0 +1,319 @@
+//Package openapi generates OpenAPI 3 documents from the routes registered on
+//an httpmux.Mux.
+//
+//The generated document is a skeleton: paths, operations, and path parameters
+//are derived from the routes, while summaries, schemas, and responses are added
+//with route metadata and the Generator's Operation hook.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gogolfing/httpmux"
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+//Route metadata keys, see httpmux.Route.WithMeta, that are copied to the
+//operations of a route. Like all metadata, they also apply to the routes
+//beneath the route they are set on.
+const (
+	//MetaSummary is the key of a string summary.
+	MetaSummary = "openapi.summary"
+
+	//MetaDescription is the key of a string description.
+	MetaDescription = "openapi.description"
+
+	//MetaTags is the key of a []string of tags.
+	MetaTags = "openapi.tags"
+
+	//MetaDeprecated is the key of a bool that marks operations deprecated.
+	MetaDeprecated = "openapi.deprecated"
+)
+
+//methods are the lower case methods that OpenAPI path items may have
+//operations for.
+var methods = map[string]bool{
+	"get":     true,
+	"put":     true,
+	"post":    true,
+	"delete":  true,
+	"options": true,
+	"head":    true,
+	"patch":   true,
+	"trace":   true,
+}
+
+type Document struct {
+	OpenAPI string              `json:"openapi"`
+	Info    Info                `json:"info"`
+	Paths   map[string]PathItem `json:"paths"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+//PathItem maps lower case methods to their Operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+
+	//Servers are the hosts the operation is served on if it is only served on
+	//some, see Generator.Generate.
+	Servers []*Server `json:"servers,omitempty"`
+}
+
+//Server is a host that operations are served on.
+type Server struct {
+	//URL is the scheme-relative URL of the host, e.g. "//{tenant}.example.com".
+	URL string `json:"url"`
+
+	Variables map[string]*ServerVariable `json:"variables,omitempty"`
+}
+
+//ServerVariable is a variable in the URL of a Server. Its Default is the name
+//of the variable since host patterns do not have default values.
+type ServerVariable struct {
+	Default string `json:"default"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+//Generator generates Documents.
+type Generator struct {
+	Info Info
+
+	//AllMethods are the methods documented for routes with a handler for all
+	//methods. It defaults to GET.
+	AllMethods []string
+
+	//Include returns whether route is documented. If it is nil, then every
+	//route is documented.
+	Include func(route httpmux.RouteInfo) bool
+
+	//Operation is called with each generated Operation so that it may be
+	//modified, e.g. to add schemas and responses.
+	Operation func(route httpmux.RouteInfo, method string, op *Operation)
+}
+
+//Generate returns a Document for the routes registered on m.
+//Methods that OpenAPI does not support are not documented.
+//
+//The operations of routes registered with a host pattern, see
+//httpmux.Mux.Host, have a Server for the host pattern. Routes with the same
+//path and method are documented as the first of them, which is the one searched
+//for first while serving, with the Servers of all of them. If any of them has
+//no host pattern, then the operation is served on every host and has no
+//Servers.
+func (g *Generator) Generate(m *httpmux.Mux) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    g.Info,
+		Paths:   map[string]PathItem{},
+	}
+
+	err := m.Walk(func(route httpmux.RouteInfo) error {
+		if g.Include != nil && !g.Include(route) {
+			return nil
+		}
+
+		path, params := convertPattern(route.Pattern)
+		for _, method := range g.routeMethods(route) {
+			lower := strings.ToLower(method)
+			if !methods[lower] {
+				continue
+			}
+			op := newOperation(route, params)
+			if g.Operation != nil {
+				g.Operation(route, method, op)
+			}
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = PathItem{}
+			}
+			doc.Paths[path][lower] = mergeOperation(doc.Paths[path][lower], op)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (g *Generator) routeMethods(route httpmux.RouteInfo) []string {
+	result := append([]string{}, route.Methods...)
+	if !route.HandlesAllMethods() {
+		return result
+	}
+	all := g.AllMethods
+	if len(all) == 0 {
+		all = []string{"GET"}
+	}
+	for _, method := range all {
+		if !containsMethod(route.Methods, method) {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+//mergeOperation returns existing, which was documented first for the same path
+//and method as op, with the Servers of op added, or op if existing is nil.
+func mergeOperation(existing, op *Operation) *Operation {
+	if existing == nil {
+		return op
+	}
+	if len(existing.Servers) == 0 || len(op.Servers) == 0 {
+		existing.Servers = nil
+		return existing
+	}
+	existing.Servers = append(existing.Servers, op.Servers...)
+	return existing
+}
+
+//newServer returns the Server of the host pattern host.
+func newServer(host string) *Server {
+	result := &Server{URL: "//" + host}
+	for _, label := range strings.Split(host, ".") {
+		if len(label) > 2 && label[0] == '{' && label[len(label)-1] == '}' {
+			if result.Variables == nil {
+				result.Variables = map[string]*ServerVariable{}
+			}
+			name := label[1 : len(label)-1]
+			result.Variables[name] = &ServerVariable{Default: name}
+		}
+	}
+	return result
+}
+
+func newOperation(route httpmux.RouteInfo, params []*Parameter) *Operation {
+	op := &Operation{
+		Responses: map[string]*Response{
+			"default": {Description: "Default response"},
+		},
+	}
+	if len(route.Host) > 0 {
+		op.Servers = []*Server{newServer(route.Host)}
+	}
+	for _, p := range params {
+		copied := *p
+		schema := *p.Schema
+		copied.Schema = &schema
+		op.Parameters = append(op.Parameters, &copied)
+	}
+
+	if summary, ok := route.Meta[MetaSummary].(string); ok {
+		op.Summary = summary
+	}
+	if description, ok := route.Meta[MetaDescription].(string); ok {
+		op.Description = description
+	}
+	if tags, ok := route.Meta[MetaTags].([]string); ok {
+		op.Tags = append([]string{}, tags...)
+	}
+	if deprecated, ok := route.Meta[MetaDeprecated].(bool); ok {
+		op.Deprecated = deprecated
+	}
+	return op
+}
+
+//convertPattern returns the OpenAPI path of pattern, with each variable
+//replaced by its name in braces, and the path Parameters of the variables.
+func convertPattern(pattern string) (string, []*Parameter) {
+	buf := &bytes.Buffer{}
+	params := []*Parameter{}
+
+	//patterns that were registered are always split without an error.
+	parts, _ := muxpath.SplitPattern(pattern)
+	for _, part := range parts {
+		name, constraint, ok := muxpath.ExtractVariableNameAndConstraint(part)
+		if !ok {
+			buf.WriteString(part)
+			continue
+		}
+		buf.WriteString("{" + name + "}")
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   constraintSchema(constraint),
+		})
+	}
+	return buf.String(), params
+}
+
+func constraintSchema(constraint string) *Schema {
+	switch constraint {
+	case "":
+		return &Schema{Type: "string"}
+	case httpmux.ConstraintInt:
+		return &Schema{Type: "integer"}
+	case httpmux.ConstraintUUID:
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+	return &Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+}
+
+//WriteJSON writes d to w as indented JSON.
+func (d *Document) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
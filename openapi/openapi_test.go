@@ -0,0 +1,199 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gogolfing/httpmux"
+)
+
+var emptyHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func newTestMux() *httpmux.Mux {
+	m := httpmux.New()
+	m.Handle("/users", emptyHandler, "GET", "POST").
+		WithMeta(MetaSummary, "Lists or creates users").
+		WithMeta(MetaTags, []string{"users"})
+	m.Handle("/users/:id{int}", emptyHandler, "GET", "DELETE").
+		WithMeta(MetaSummary, "Reads or deletes a user").
+		WithMeta(MetaDeprecated, true)
+	m.Handle("/users/:id{int}/keys/:key{uuid}", emptyHandler, "GET")
+	m.Handle("/tags/:slug{[a-z-]+}", emptyHandler)
+	m.Handle("/files/*path", emptyHandler, "GET", "PURGE")
+	m.Handle("/internal", emptyHandler, "GET")
+	return m
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	g := &Generator{
+		Info: Info{Title: "Test", Version: "1.0"},
+		Include: func(route httpmux.RouteInfo) bool {
+			return route.Pattern != "/internal"
+		},
+		Operation: func(route httpmux.RouteInfo, method string, op *Operation) {
+			if route.Pattern == "/users" && method == "POST" {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content: map[string]*MediaType{
+						"application/json": {Schema: &Schema{Ref: "#/components/schemas/User"}},
+					},
+				}
+			}
+		},
+	}
+
+	doc, err := g.Generate(newTestMux())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaultResponses := map[string]*Response{"default": {Description: "Default response"}}
+	idParam := &Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}
+	want := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "Test", Version: "1.0"},
+		Paths: map[string]PathItem{
+			"/users": {
+				"get": {Summary: "Lists or creates users", Tags: []string{"users"}, Responses: defaultResponses},
+				"post": {
+					Summary: "Lists or creates users",
+					Tags:    []string{"users"},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content: map[string]*MediaType{
+							"application/json": {Schema: &Schema{Ref: "#/components/schemas/User"}},
+						},
+					},
+					Responses: defaultResponses,
+				},
+			},
+			"/users/{id}": {
+				"get":    {Summary: "Reads or deletes a user", Tags: []string{"users"}, Parameters: []*Parameter{idParam}, Responses: defaultResponses, Deprecated: true},
+				"delete": {Summary: "Reads or deletes a user", Tags: []string{"users"}, Parameters: []*Parameter{idParam}, Responses: defaultResponses, Deprecated: true},
+			},
+			"/users/{id}/keys/{key}": {
+				"get": {
+					Summary:    "Reads or deletes a user",
+					Tags:       []string{"users"},
+					Deprecated: true,
+					Parameters: []*Parameter{
+						idParam,
+						{Name: "key", In: "path", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}},
+					},
+					Responses: defaultResponses,
+				},
+			},
+			"/tags/{slug}": {
+				"get": {
+					Parameters: []*Parameter{
+						{Name: "slug", In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: "^(?:[a-z-]+)$"}},
+					},
+					Responses: defaultResponses,
+				},
+			},
+			"/files/{path}": {
+				"get": {
+					Parameters: []*Parameter{{Name: "path", In: "path", Required: true, Schema: &Schema{Type: "string"}}},
+					Responses:  defaultResponses,
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(doc, want) {
+		got, _ := json.MarshalIndent(doc, "", "  ")
+		expected, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("Generate() = %s WANT %s", got, expected)
+	}
+}
+
+func TestGenerator_Generate_MergesOperationsOfHosts(t *testing.T) {
+	m := httpmux.New()
+	m.Host("{tenant}.example.com").SubRoute("/users").Handle(emptyHandler, "GET", "POST")
+	m.Host("admin.example.com").SubRoute("/users").Handle(emptyHandler, "GET")
+	m.Host("www.example.com").SubRoute("/about").Handle(emptyHandler, "GET")
+	m.Handle("/about", emptyHandler, "GET")
+
+	doc, err := (&Generator{}).Generate(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant := &Server{URL: "//{tenant}.example.com", Variables: map[string]*ServerVariable{"tenant": {Default: "tenant"}}}
+	admin := &Server{URL: "//admin.example.com"}
+	servers := map[string][]*Server{
+		"get /users":  {tenant, admin},
+		"post /users": {tenant},
+		"get /about":  nil,
+	}
+	for key, want := range servers {
+		parts := strings.Split(key, " ")
+		op := doc.Paths[parts[1]][parts[0]]
+		if op == nil || !reflect.DeepEqual(op.Servers, want) {
+			t.Errorf("%v: Servers = %v WANT %v", key, op, want)
+		}
+	}
+}
+
+func TestDocument_WriteJSON(t *testing.T) {
+	doc, _ := (&Generator{Info: Info{Title: "Test", Version: "1.0"}}).Generate(newTestMux())
+
+	buf := &bytes.Buffer{}
+	if err := doc.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Document{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, doc) {
+		t.Errorf("decoded JSON = %v WANT %v", decoded, doc)
+	}
+}
+
+func TestDocument_WriteYAML(t *testing.T) {
+	m := httpmux.New()
+	m.Handle("/users/:id{int}", emptyHandler, "GET").WithMeta(MetaTags, []string{"users", "read"})
+	m.Handle("/empty", emptyHandler, "GET")
+
+	doc, _ := (&Generator{Info: Info{Title: "Test: \"quoted\"", Version: "1.0"}}).Generate(m)
+	doc.Paths["/empty"]["get"].Responses = map[string]*Response{}
+
+	buf := &bytes.Buffer{}
+	if err := doc.WriteYAML(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `"info":
+  "title": "Test: \"quoted\""
+  "version": "1.0"
+"openapi": "3.0.3"
+"paths":
+  "/empty":
+    "get":
+      "responses": {}
+  "/users/{id}":
+    "get":
+      "parameters":
+        -
+          "in": "path"
+          "name": "id"
+          "required": true
+          "schema":
+            "type": "integer"
+      "responses":
+        "default":
+          "description": "Default response"
+      "tags":
+        - "users"
+        - "read"
+`
+	if result := buf.String(); result != want {
+		t.Errorf("WriteYAML() = %s WANT %s", result, want)
+	}
+}
@@ -0,0 +1,23 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/gogolfing/httpmux/internal/yaml"
+)
+
+//WriteYAML writes d to w as YAML.
+//Object keys are sorted and all strings are double quoted.
+func (d *Document) WriteYAML(w io.Writer) error {
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return err
+	}
+	_, err = w.Write(yaml.Marshal(value))
+	return err
+}
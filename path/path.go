@@ -1,7 +1,10 @@
 package path
 
 import (
+	"errors"
+	"fmt"
 	pathlib "path"
+	"strconv"
 	"strings"
 )
 
@@ -11,8 +14,99 @@ const (
 
 	SegmentVarRune = ':'
 	EndVarRune     = '*'
+
+	ConstraintStartRune = '{'
+	ConstraintEndRune   = '}'
+
+	OptionalStartRune = '['
+	OptionalEndRune   = ']'
 )
 
+//ErrUnbalancedOptional is returned by ExpandOptionalParts when a path's
+//optional part brackets are not balanced.
+var ErrUnbalancedOptional = errors.New("httpmux/path: unbalanced optional part brackets")
+
+//ExpandOptionalParts returns every path that path describes when each of its
+//optional parts, enclosed in brackets as in "/docs[/:page[/:section]]", is
+//either included or omitted. Optional parts may be nested, and brackets inside
+//of constraints do not start optional parts.
+//
+//The first path returned omits every optional part. A path without optional
+//parts is returned as the only result.
+func ExpandOptionalParts(path string) ([]string, error) {
+	if strings.IndexAny(path, string([]rune{OptionalStartRune, OptionalEndRune})) < 0 {
+		return []string{path}, nil
+	}
+	result, end, err := expandOptionalSequence(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(path) { //closing bracket without an opening one.
+		return nil, ErrUnbalancedOptional
+	}
+	return removeDuplicates(result), nil
+}
+
+//expandOptionalSequence expands path from start up to the closing bracket of
+//the optional part it is in, or the end of path. It returns the expansions and
+//the index it stopped at.
+func expandOptionalSequence(path string, start int) ([]string, int, error) {
+	result := []string{""}
+	depth := 0
+	i := start
+	for i < len(path) {
+		switch c := path[i]; {
+		case c == ConstraintStartRune:
+			depth++
+		case c == ConstraintEndRune && depth > 0:
+			depth--
+		case c == OptionalEndRune && depth == 0:
+			result = appendToEach(result, path[start:i])
+			return result, i, nil
+		case c == OptionalStartRune && depth == 0:
+			result = appendToEach(result, path[start:i])
+			optional, end, err := expandOptionalSequence(path, i+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if end == len(path) { //opening bracket without a closing one.
+				return nil, 0, ErrUnbalancedOptional
+			}
+			expanded := make([]string, 0, len(result)*(len(optional)+1))
+			for _, r := range result {
+				expanded = append(expanded, r)
+				for _, o := range optional {
+					expanded = append(expanded, r+o)
+				}
+			}
+			result = expanded
+			i, start = end+1, end+1
+			continue
+		}
+		i++
+	}
+	return appendToEach(result, path[start:]), len(path), nil
+}
+
+func appendToEach(values []string, suffix string) []string {
+	for i := range values {
+		values[i] += suffix
+	}
+	return values
+}
+
+func removeDuplicates(values []string) []string {
+	result := values[:0]
+	seen := map[string]bool{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 func SplitIntoStaticAndVariableParts(path string) []string {
 	result := []string{}
 	static, remaining := "", path
@@ -28,7 +122,7 @@ func SplitIntoStaticAndVariableParts(path string) []string {
 			static, remaining = static+remaining[:varIndex+1], remaining[varIndex+2:]
 
 		case remaining[varIndex] == SegmentVarRune: //found segment variable
-			slashIndex := strings.IndexRune(remaining[varIndex:], SlashRune)
+			slashIndex := indexOfSegmentVariableEnd(remaining[varIndex:])
 			if slashIndex < 0 {
 				slashIndex = len(remaining)
 			} else {
@@ -55,6 +149,133 @@ func SplitIntoStaticAndVariableParts(path string) []string {
 	return result
 }
 
+//indexOfSegmentVariableEnd returns the index of the first slash in value that
+//is not inside of a constraint, or -1 if there is no such slash.
+func indexOfSegmentVariableEnd(value string) int {
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case ConstraintStartRune:
+			depth++
+		case ConstraintEndRune:
+			if depth > 0 {
+				depth--
+			}
+		case SlashRune:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+//ErrInvalidVariableName is returned by SplitPattern for a variable whose name is
+//empty or is followed by a character that cannot end it. It is the variable as
+//it appears in the pattern, up to the next slash.
+type ErrInvalidVariableName string
+
+func (e ErrInvalidVariableName) Error() string {
+	return fmt.Sprintf("httpmux/path: invalid variable name in %q", string(e))
+}
+
+//SplitPattern splits pattern into static and variable parts, allowing more
+//than one part within a segment, as in "/files/:name.:ext" or
+//"/v:major.:minor/".
+//
+//A segment variable's name consists of ASCII letters, digits, and underscores,
+//and may contain single dashes between them, e.g. ":user-id". The name may be
+//followed by a constraint in braces. A name without a constraint must be
+//followed by a slash, a '.' or '-' that starts a static part, another
+//variable, or the end of pattern. An end variable's name reads until the end of
+//pattern. An ErrInvalidVariableName is returned for other characters following
+//a name, for empty names, and for unbalanced constraint braces.
+func SplitPattern(pattern string) ([]string, error) {
+	result := []string{}
+	static := &strings.Builder{}
+	appendStatic := func() {
+		if static.Len() > 0 {
+			result = append(result, static.String())
+			static.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c != SegmentVarRune && c != EndVarRune:
+			static.WriteByte(c)
+			i++
+			continue
+		case i+1 < len(pattern) && pattern[i+1] == c: //double variable rune. reduce to one.
+			static.WriteByte(c)
+			i += 2
+			continue
+		}
+
+		appendStatic()
+		end := len(pattern)
+		if c == SegmentVarRune {
+			end = i + indexOfVariableNameEnd(pattern[i:])
+		}
+		if name, ok := ExtractVariableName(pattern[i:end]); !ok || len(name) == 0 {
+			return nil, newErrInvalidVariableName(pattern[i:])
+		}
+		result = append(result, pattern[i:end])
+		i = end
+	}
+	appendStatic()
+
+	return result, nil
+}
+
+//indexOfVariableNameEnd returns the index of the first byte after the name and
+//constraint of the segment variable that value starts with. It returns 0 if the
+//variable is invalid.
+func indexOfVariableNameEnd(value string) int {
+	i := 1
+	for i < len(value) && isNameByte(value[i]) {
+		i++
+		if i+1 < len(value) && value[i] == '-' && isNameByte(value[i+1]) {
+			i++
+		}
+	}
+	if i == 1 || i == len(value) {
+		return i
+	}
+
+	switch value[i] {
+	case SlashRune, SegmentVarRune, EndVarRune, '.', '-':
+		return i
+	case ConstraintStartRune:
+		depth := 0
+		for ; i < len(value); i++ {
+			switch value[i] {
+			case ConstraintStartRune:
+				depth++
+			case ConstraintEndRune:
+				depth--
+			}
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+func newErrInvalidVariableName(value string) ErrInvalidVariableName {
+	if index := strings.IndexRune(value, SlashRune); index >= 0 {
+		value = value[:index]
+	}
+	return ErrInvalidVariableName(value)
+}
+
+//isNameByte returns whether b may be part of a segment variable's name.
+func isNameByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
 func staticThenVariableParts(path, static string, startIndex, varIndex, varEnd int) []string {
 	if startIndex == varIndex {
 		return []string{static + path[varIndex:varEnd]}
@@ -63,10 +284,24 @@ func staticThenVariableParts(path, static string, startIndex, varIndex, varEnd i
 }
 
 func ExtractVariableName(value string) (name string, ok bool) {
-	if len(value) > 0 && (value[0] == SegmentVarRune || value[0] == EndVarRune) {
-		return value[1:], true
+	name, _, ok = ExtractVariableNameAndConstraint(value)
+	return
+}
+
+//ExtractVariableNameAndConstraint returns the name and constraint of the
+//variable part value.
+//The constraint is the text between a trailing pair of braces as in
+//":id{int}" or "*file{.+\.txt}". It is empty if no constraint is present.
+func ExtractVariableNameAndConstraint(value string) (name, constraint string, ok bool) {
+	if len(value) == 0 || (value[0] != SegmentVarRune && value[0] != EndVarRune) {
+		return "", "", false
 	}
-	return "", false
+	name = value[1:]
+	startIndex := strings.IndexRune(name, ConstraintStartRune)
+	if startIndex < 0 || name[len(name)-1] != ConstraintEndRune {
+		return name, "", true
+	}
+	return name[:startIndex], name[startIndex+1 : len(name)-1], true
 }
 
 func IsSegmentVariable(value string) bool {
@@ -81,11 +316,96 @@ func Clean(path string) string {
 	path = EnsureRootSlash(path)
 	newPath := pathlib.Clean(path)
 	if path[len(path)-1] == SlashRune && newPath != Slash {
+		if newPath == path[:len(path)-1] { //already clean. avoids allocating.
+			return path
+		}
 		newPath += Slash
 	}
 	return newPath
 }
 
+//CleanPattern is the same as Clean except that the constraints of variables in
+//pattern are left unchanged, e.g. the slashes and dots in ":name{[a-z]+/\.\.}".
+func CleanPattern(pattern string) string {
+	ranges := constraintRanges(pattern)
+	if len(ranges) == 0 {
+		return Clean(pattern)
+	}
+
+	//replace each constraint with a placeholder that Clean leaves unchanged.
+	buf := &strings.Builder{}
+	last := 0
+	for i, r := range ranges {
+		buf.WriteString(pattern[last:r[0]])
+		buf.WriteString(string(ConstraintStartRune) + strconv.Itoa(i) + string(ConstraintEndRune))
+		last = r[1]
+	}
+	buf.WriteString(pattern[last:])
+	cleaned := Clean(buf.String())
+
+	buf.Reset()
+	last = 0
+	for _, r := range constraintRanges(cleaned) {
+		buf.WriteString(cleaned[last:r[0]])
+		index, err := strconv.Atoi(cleaned[r[0]+1 : r[1]-1])
+		if err != nil || index < 0 || index >= len(ranges) {
+			buf.WriteString(cleaned[r[0]:r[1]])
+		} else {
+			buf.WriteString(pattern[ranges[index][0]:ranges[index][1]])
+		}
+		last = r[1]
+	}
+	buf.WriteString(cleaned[last:])
+	return buf.String()
+}
+
+//constraintRanges returns the start and end indexes, including the braces, of
+//the constraints of the variables in pattern.
+func constraintRanges(pattern string) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != SegmentVarRune && pattern[i] != EndVarRune {
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == pattern[i] { //escaped rune.
+			i++
+			continue
+		}
+		if pattern[i] == EndVarRune {
+			start := strings.IndexRune(pattern[i:], ConstraintStartRune)
+			if start >= 0 && pattern[len(pattern)-1] == ConstraintEndRune {
+				result = append(result, [2]int{i + start, len(pattern)})
+			}
+			return result
+		}
+		j := i + 1
+		for j < len(pattern) && pattern[j] != SlashRune && pattern[j] != ConstraintStartRune {
+			j++
+		}
+		if j == len(pattern) || pattern[j] != ConstraintStartRune {
+			i = j - 1
+			continue
+		}
+		end, depth := j, 0
+		for ; end < len(pattern); end++ {
+			if pattern[end] == ConstraintStartRune {
+				depth++
+			} else if pattern[end] == ConstraintEndRune {
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		if end == len(pattern) { //unbalanced braces. nothing more to protect.
+			return result
+		}
+		result = append(result, [2]int{j, end + 1})
+		i = end
+	}
+	return result
+}
+
 func EnsureRootSlash(path string) string {
 	if len(path) == 0 {
 		return Slash
@@ -109,6 +429,32 @@ func CompareAfterPrefix(a, b string) (comparison, prefixLen int) {
 	return int(a[i] - b[i]), i
 }
 
+//HasPrefixFold returns whether s begins with prefix ignoring the case of ASCII
+//letters.
+func HasPrefixFold(s, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if s[i] != prefix[i] && ToggleCase(s[i]) != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//ToggleCase returns the upper case of an ASCII lower case letter and the lower
+//case of an ASCII upper case letter. All other bytes are returned unchanged.
+func ToggleCase(b byte) byte {
+	switch {
+	case 'a' <= b && b <= 'z':
+		return b - 'a' + 'A'
+	case 'A' <= b && b <= 'Z':
+		return b - 'A' + 'a'
+	}
+	return b
+}
+
 func CommonPrefixLen(a, b string) int {
 	i := 0
 	for ; i < len(a) && i < len(b) && a[i] == b[i]; i++ {
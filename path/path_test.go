@@ -39,6 +39,11 @@ func TestSplitIntoStaticAndVariableParts(t *testing.T) {
 		{"/**:foo", []string{"/*", ":foo"}},
 		{`/:_)(*&_%)&#@_) @(&1023495870124:POIHJIOUH______++_+_\\'/`, []string{"/", `:_)(*&_%)&#@_) @(&1023495870124:POIHJIOUH______++_+_\\'`, "/"}},
 
+		{"/:id{int}/bar", []string{"/", ":id{int}", "/bar"}},
+		{"/:slug{[a-z/]+}/bar", []string{"/", ":slug{[a-z/]+}", "/bar"}},
+		{"/:slug{a{1,2}}", []string{"/", ":slug{a{1,2}}"}},
+		{"/*file{.+/x}", []string{"/", "*file{.+/x}"}},
+
 		{"/*foo/bar", []string{"/", "*foo/bar"}},
 		{"*foo/bar", []string{"*foo/bar"}},
 		{"*foo:bar", []string{"*foo:bar"}},
@@ -56,6 +61,50 @@ func TestSplitIntoStaticAndVariableParts(t *testing.T) {
 	}
 }
 
+func TestSplitPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		result  []string
+		err     error
+	}{
+		{"", []string{}, nil},
+		{"/foo::bar**", []string{"/foo:bar*"}, nil},
+		{"/:foo/bar", []string{"/", ":foo", "/bar"}, nil},
+		{"/:foo/:bar/:else/prefix:more", []string{"/", ":foo", "/", ":bar", "/", ":else", "/prefix", ":more"}, nil},
+		{"/:::foo", []string{"/:", ":foo"}, nil},
+		{"/*file/x", []string{"/", "*file/x"}, nil},
+
+		{"/files/:name.:ext", []string{"/files/", ":name", ".", ":ext"}, nil},
+		{"/v:major.:minor/", []string{"/v", ":major", ".", ":minor", "/"}, nil},
+		{"/:from-:to", []string{"/", ":from", "-", ":to"}, nil},
+		{"/users/:user-id/posts", []string{"/users/", ":user-id", "/posts"}, nil},
+		{"/:a_B9-c/", []string{"/", ":a_B9-c", "/"}, nil},
+		{"/:a-", []string{"/", ":a", "-"}, nil},
+		{"/:foo:bar", []string{"/", ":foo", ":bar"}, nil},
+		{"/:foo*bar", []string{"/", ":foo", "*bar"}, nil},
+		{"/:id{int}.json", []string{"/", ":id{int}", ".json"}, nil},
+		{"/:id{int}x/y", []string{"/", ":id{int}", "x/y"}, nil},
+		{"/:slug{[a-z/]+}/bar", []string{"/", ":slug{[a-z/]+}", "/bar"}, nil},
+
+		{"/:", nil, ErrInvalidVariableName(":")},
+		{"/:/x", nil, ErrInvalidVariableName(":")},
+		{"/x/:.json", nil, ErrInvalidVariableName(":.json")},
+		{"/:{int}", nil, ErrInvalidVariableName(":{int}")},
+		{"/*", nil, ErrInvalidVariableName("*")},
+		{"/*{.+}", nil, ErrInvalidVariableName("*{.+}")},
+		{"/:id{int", nil, ErrInvalidVariableName(":id{int")},
+		{"/: :X", nil, ErrInvalidVariableName(": :X")},
+		{"/:foo bar/x", nil, ErrInvalidVariableName(":foo bar")},
+		{"/:user@host", nil, ErrInvalidVariableName(":user@host")},
+	}
+	for _, test := range tests {
+		result, err := SplitPattern(test.pattern)
+		if !reflect.DeepEqual(result, test.result) || err != test.err {
+			t.Errorf("SplitPattern(%q) = %q, %v WANT %q, %v", test.pattern, result, err, test.result, test.err)
+		}
+	}
+}
+
 func TestExtractVariableName(t *testing.T) {
 	tests := []struct {
 		value        string
@@ -88,6 +137,42 @@ func TestExtractVariableName(t *testing.T) {
 	}
 }
 
+func TestExtractVariableNameAndConstraint(t *testing.T) {
+	tests := []struct {
+		value      string
+		name       string
+		constraint string
+		ok         bool
+	}{
+		{"", "", "", false},
+		{"foobar", "", "", false},
+		{"{int}", "", "", false},
+		{":", "", "", true},
+		{":id", "id", "", true},
+		{":id{int}", "id", "int", true},
+		{":{uuid}", "", "uuid", true},
+		{"*file{.+\\.txt}", "file", ".+\\.txt", true},
+		{":slug{a{1,2}}", "slug", "a{1,2}", true},
+		{":id{int", "id{int", "", true},
+		{":id}", "id}", "", true},
+	}
+	for _, test := range tests {
+		name, constraint, ok := ExtractVariableNameAndConstraint(test.value)
+		if name != test.name || constraint != test.constraint || ok != test.ok {
+			t.Errorf(
+				"ExtractVariableNameAndConstraint(%q) = %q, %q, %v WANT %q, %q, %v",
+				test.value,
+				name,
+				constraint,
+				ok,
+				test.name,
+				test.constraint,
+				test.ok,
+			)
+		}
+	}
+}
+
 func TestIsSegmentVariable(t *testing.T) {
 	tests := []struct {
 		value  string
@@ -170,6 +255,29 @@ func TestClean(t *testing.T) {
 	}
 }
 
+func TestCleanPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		cleaned string
+	}{
+		{"", "/"},
+		{"hello/../world/", "/world/"},
+		{"/:id{int}", "/:id{int}"},
+		{"/a/:name{[a-z]+/\\.\\./x}/./b", "/a/:name{[a-z]+/\\.\\./x}/b"},
+		{"/a/../:x{.//..}/*rest{.*//..}", "/:x{.//..}/*rest{.*//..}"},
+		{"/a/:x{{1}}/..", "/a"},
+		{"/a/:x{{1}}/../:y{a//b}", "/a/:y{a//b}"},
+		{"/a/::x{//}", "/a/::x{/}"},
+		{"/a/:x{//", "/a/:x{/"},
+	}
+	for _, test := range tests {
+		cleaned := CleanPattern(test.pattern)
+		if cleaned != test.cleaned {
+			t.Errorf("CleanPattern(%q) = %q WANT %q", test.pattern, cleaned, test.cleaned)
+		}
+	}
+}
+
 func TestEnsureRootSlash(t *testing.T) {
 	tests := []struct {
 		path   string
@@ -238,3 +346,77 @@ func TestCompareIgnorePrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestHasPrefixFold(t *testing.T) {
+	tests := []struct {
+		s      string
+		prefix string
+		result bool
+	}{
+		{"", "", true},
+		{"/users", "", true},
+		{"", "/users", false},
+		{"/users/1", "/users", true},
+		{"/USERS/1", "/users", true},
+		{"/uSeRs", "/UsErS", true},
+		{"/use", "/users", false},
+		{"/usera", "/users", false},
+		{"[", "{", false},
+		{"@", "`", false},
+	}
+	for _, test := range tests {
+		if result := HasPrefixFold(test.s, test.prefix); result != test.result {
+			t.Errorf("HasPrefixFold(%q, %q) = %v WANT %v", test.s, test.prefix, result, test.result)
+		}
+	}
+}
+
+func TestToggleCase(t *testing.T) {
+	tests := []struct {
+		b      byte
+		result byte
+	}{
+		{'a', 'A'},
+		{'z', 'Z'},
+		{'A', 'a'},
+		{'Z', 'z'},
+		{'0', '0'},
+		{'/', '/'},
+		{'@', '@'},
+		{'[', '['},
+		{'`', '`'},
+		{'{', '{'},
+	}
+	for _, test := range tests {
+		if result := ToggleCase(test.b); result != test.result {
+			t.Errorf("ToggleCase(%q) = %q WANT %q", test.b, result, test.result)
+		}
+	}
+}
+
+func TestExpandOptionalParts(t *testing.T) {
+	tests := []struct {
+		path   string
+		result []string
+		err    error
+	}{
+		{"", []string{""}, nil},
+		{"/docs", []string{"/docs"}, nil},
+		{"/docs[/:page]", []string{"/docs", "/docs/:page"}, nil},
+		{"/docs[/:page[/:section]]", []string{"/docs", "/docs/:page", "/docs/:page/:section"}, nil},
+		{"/a[/b][/c]", []string{"/a", "/a/c", "/a/b", "/a/b/c"}, nil},
+		{"/a[/b]/c", []string{"/a/c", "/a/b/c"}, nil},
+		{"/a[]", []string{"/a"}, nil},
+		{"/users/:id{[0-9]+}[/posts]", []string{"/users/:id{[0-9]+}", "/users/:id{[0-9]+}/posts"}, nil},
+		{"/a[/b", nil, ErrUnbalancedOptional},
+		{"/a]/b", nil, ErrUnbalancedOptional},
+		{"/a[/b]]", nil, ErrUnbalancedOptional},
+		{"/a[[/b]", nil, ErrUnbalancedOptional},
+	}
+	for _, test := range tests {
+		result, err := ExpandOptionalParts(test.path)
+		if !reflect.DeepEqual(result, test.result) || err != test.err {
+			t.Errorf("ExpandOptionalParts(%q) = %q, %v WANT %q, %v", test.path, result, err, test.result, test.err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package httpmux
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeProblemJSON = "application/problem+json"
+
+	headerAccept = "Accept"
+)
+
+//Problem is an RFC 7807 problem details object. It is served by the Mux's
+//default error handlers if ProblemDetails is set, and may also be used as any
+//of the Mux's error handlers.
+//
+//A Problem is served as application/problem+json unless the request's Accept
+//header prefers text/plain or text/html to JSON, in which case Title is served
+//as text/plain like http.Error. Media types with the +json suffix, e.g.
+//application/vnd.api+json, are JSON, and problem+json is also served if
+//neither text type is acceptable.
+type Problem struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Detail string `json:"detail,omitempty"`
+
+	//Instance is the path of the request if it is empty when served.
+	Instance string `json:"instance,omitempty"`
+
+	//Allow are the allowed methods of Method Not Allowed problems.
+	Allow []string `json:"allow,omitempty"`
+}
+
+//newProblem returns the Problem for err, which is one of ErrNotFound, an
+//ErrMethodNotAllowed, or an ErrNotMatched.
+func newProblem(err error) *Problem {
+	status := http.StatusInternalServerError
+	var allow []string
+	switch err := err.(type) {
+	case ErrStatusHandler:
+		status = int(err)
+	case ErrMethodNotAllowed:
+		status, allow = http.StatusMethodNotAllowed, err
+	case ErrNotMatched:
+		status = int(err)
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Allow:  allow,
+	}
+}
+
+func (p *Problem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Add("Vary", headerAccept)
+
+	if !prefersProblemJSON(r.Header.Get(headerAccept)) {
+		http.Error(w, p.Title, status)
+		return
+	}
+
+	problem := *p
+	if len(problem.Instance) == 0 {
+		problem.Instance = r.URL.EscapedPath()
+	}
+	body, err := json.Marshal(&problem)
+	if err != nil {
+		http.Error(w, p.Title, status)
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+//problemOr returns a Problem for err in place of handler if m has
+//ProblemDetails set and handler is one of the plain text error handlers. The
+//Problem has the status of handler, e.g. a NotFoundHandler of
+//ErrStatusHandler(http.StatusGone) is served as a 410 Problem.
+func (m *Mux) problemOr(handler http.Handler, err error) http.Handler {
+	if !m.ProblemDetails {
+		return handler
+	}
+	var status int
+	switch handler := handler.(type) {
+	case ErrStatusHandler:
+		status = int(handler)
+	case ErrNotMatched:
+		status = int(handler)
+	default:
+		return handler
+	}
+	problem := newProblem(err)
+	if status != problem.Status {
+		problem.Title, problem.Status, problem.Allow = http.StatusText(status), status, nil
+	}
+	return problem
+}
+
+//prefersProblemJSON returns whether accept, the value of an Accept header,
+//prefers JSON to text. An empty accept, one that accepts both equally, or one
+//that accepts neither text/plain nor text/html prefers JSON. JSON is
+//application/json and every application/*+json media type.
+func prefersProblemJSON(accept string) bool {
+	if len(strings.TrimSpace(accept)) == 0 {
+		return true
+	}
+	jsonQ, jsonSpecificity := acceptQuality(accept, jsonMediaTypes(accept)...)
+	textQ, textSpecificity := acceptQuality(accept, "text/plain", "text/html")
+	if textQ == 0 {
+		return true
+	}
+	if jsonQ != textQ {
+		return jsonQ > textQ
+	}
+	return jsonSpecificity >= textSpecificity
+}
+
+//jsonMediaTypes returns application/problem+json, application/json, and the
+//media types in accept with the structured syntax suffix +json.
+func jsonMediaTypes(accept string) []string {
+	result := []string{ContentTypeProblemJSON, "application/json"}
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err == nil && strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json") {
+			result = append(result, mediaType)
+		}
+	}
+	return result
+}
+
+//acceptQuality returns the highest quality that accept gives any of
+//mediaTypes, and the specificity of the media range that gave it: 2 for an
+//exact match, 1 for type/*, and 0 for */*. The quality of a media type is
+//given by its most specific matching media range.
+func acceptQuality(accept string, mediaTypes ...string) (quality float64, specificity int) {
+	for _, mediaType := range mediaTypes {
+		q, s := 0.0, -1
+		for _, mediaRange := range strings.Split(accept, ",") {
+			rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || !mediaRangeMatches(rangeType, mediaType) {
+				continue
+			}
+			if rangeS := mediaRangeSpecificity(rangeType, mediaType); rangeS > s {
+				q, s = mediaRangeQuality(params), rangeS
+			}
+		}
+		if q > quality || (q == quality && s > specificity) {
+			quality, specificity = q, s
+		}
+	}
+	if quality <= 0 {
+		return 0, 0
+	}
+	return quality, specificity
+}
+
+//mediaRangeSpecificity returns the specificity of mediaRange, which must
+//match mediaType.
+func mediaRangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case strings.EqualFold(mediaRange, mediaType):
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	}
+	return 1
+}
+
+//mediaRangeQuality returns the quality in the params of a media range.
+func mediaRangeQuality(params map[string]string) float64 {
+	q, ok := params["q"]
+	if !ok {
+		return 1
+	}
+	quality, err := strconv.ParseFloat(q, 64)
+	if err != nil {
+		return 0
+	}
+	return quality
+}
@@ -0,0 +1,175 @@
+package httpmux
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMux_ServeHTTP_ProblemDetails(t *testing.T) {
+	m := New()
+	m.ProblemDetails = true
+	m.Handle("/users/:id", TestHandler("USER"), "GET", "PUT")
+	m.SubRoute("/json").Match(ContentType("application/json")).Handle(TestHandler("JSON"), "POST")
+
+	tests := []struct {
+		method  string
+		path    string
+		accept  string
+		status  int
+		problem *Problem
+		body    string
+	}{
+		{
+			method: "GET",
+			path:   "/missing",
+			status: http.StatusNotFound,
+			problem: &Problem{
+				Type:     "about:blank",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Instance: "/missing",
+			},
+		},
+		{
+			method: "DELETE",
+			path:   "/users/a%20b",
+			accept: "application/json",
+			status: http.StatusMethodNotAllowed,
+			problem: &Problem{
+				Type:     "about:blank",
+				Title:    "Method Not Allowed",
+				Status:   http.StatusMethodNotAllowed,
+				Instance: "/users/a%20b",
+				Allow:    []string{"GET", "PUT"},
+			},
+		},
+		{
+			method: "POST",
+			path:   "/json",
+			accept: "*/*",
+			status: http.StatusUnsupportedMediaType,
+			problem: &Problem{
+				Type:     "about:blank",
+				Title:    "Unsupported Media Type",
+				Status:   http.StatusUnsupportedMediaType,
+				Instance: "/json",
+			},
+		},
+		{
+			method: "GET",
+			path:   "/missing",
+			accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			status: http.StatusNotFound,
+			body:   "Not Found\n",
+		},
+		{
+			method: "DELETE",
+			path:   "/users/1",
+			accept: "application/json;q=0.5, text/plain",
+			status: http.StatusMethodNotAllowed,
+			body:   "Method Not Allowed\n",
+		},
+	}
+
+	for i, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if len(test.accept) > 0 {
+			r.Header.Set("Accept", test.accept)
+		}
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%d: status = %d WANT %d", i, w.Code, test.status)
+		}
+		if test.status == http.StatusMethodNotAllowed && w.Header().Get(HeaderAllow) != "GET, PUT" {
+			t.Errorf("%d: Allow = %q WANT %q", i, w.Header().Get(HeaderAllow), "GET, PUT")
+		}
+		if test.problem == nil {
+			if w.Body.String() != test.body {
+				t.Errorf("%d: body = %q WANT %q", i, w.Body.String(), test.body)
+			}
+			continue
+		}
+
+		if contentType := w.Header().Get("Content-Type"); contentType != ContentTypeProblemJSON {
+			t.Errorf("%d: Content-Type = %q WANT %q", i, contentType, ContentTypeProblemJSON)
+		}
+		problem := &Problem{}
+		if err := json.Unmarshal(w.Body.Bytes(), problem); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(problem, test.problem) {
+			t.Errorf("%d: problem = %v WANT %v", i, problem, test.problem)
+		}
+	}
+}
+
+func TestMux_ServeHTTP_ProblemDetailsKeepsCustomHandlers(t *testing.T) {
+	m := New()
+	m.ProblemDetails = true
+	m.NotFoundHandler = TestHandler("CUSTOM")
+
+	testMux_ServeHTTP(t, m, &ServeHTTPTest{Method: "GET", Path: "/", Status: http.StatusOK, Body: "CUSTOM"})
+}
+
+func TestMux_ServeHTTP_ProblemDetailsUsesTheStatusOfErrorHandlers(t *testing.T) {
+	m := New()
+	m.ProblemDetails = true
+	m.NotFoundHandler = ErrStatusHandler(http.StatusGone)
+	m.MethodNotAllowedHandler = ErrStatusHandler(http.StatusNotFound)
+	m.Handle("/users", TestHandler("USERS"), "GET")
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/missing", http.StatusGone},
+		{"POST", "/users", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+
+		problem := &Problem{}
+		if err := json.Unmarshal(w.Body.Bytes(), problem); err != nil {
+			t.Fatalf("%v %v: %v", test.method, test.path, err)
+		}
+		want := &Problem{Type: "about:blank", Title: http.StatusText(test.status), Status: test.status, Instance: test.path}
+		if w.Code != test.status || !reflect.DeepEqual(problem, want) {
+			t.Errorf("%v %v = %v, %v WANT %v, %v", test.method, test.path, w.Code, problem, test.status, want)
+		}
+	}
+}
+
+func TestPrefersProblemJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		result bool
+	}{
+		{"", true},
+		{"*/*", true},
+		{"application/json", true},
+		{"application/problem+json, text/plain;q=0.9", true},
+		{"text/plain", false},
+		{"text/*", false},
+		{"application/*;q=0.5, text/*;q=0.4", true},
+		{"text/plain, application/json", true},
+		{"application/*;q=0, */*", false},
+		{"image/png", true},
+		{"application/xml", true},
+		{"application/vnd.api+json", true},
+		{"text/plain;q=0.5, application/vnd.api+json", true},
+		{"text/plain, application/vnd.api+json;q=0.5", false},
+		{"text/plain;q=0, */*", true},
+	}
+	for _, test := range tests {
+		if result := prefersProblemJSON(test.accept); result != test.result {
+			t.Errorf("prefersProblemJSON(%q) = %v WANT %v", test.accept, result, test.result)
+		}
+	}
+}
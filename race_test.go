@@ -0,0 +1,7 @@
+//go:build race
+
+package httpmux
+
+//raceEnabled is true when testing with the race detector, which makes
+//sync.Pool drop items at random and so allocation counts unreliable.
+const raceEnabled = true
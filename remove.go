@@ -0,0 +1,128 @@
+package httpmux
+
+import muxpath "github.com/gogolfing/httpmux/path"
+
+//Remove removes the handlers registered at path for methods, or all handlers
+//at path if no methods are given. Routes registered with Host are unaffected,
+//see Route.Remove to remove them.
+//It returns whether any handler was registered at path before the call.
+//If path has optional parts, then every path it expands into is removed.
+//Remove panics with the same error as Handle if path's optional parts are
+//malformed, e.g. have unbalanced brackets.
+//
+//Nodes in the routing tree that are left without handlers or descendants are
+//removed, so that path may be registered again with, for example, a different
+//variable name. Routes previously obtained from m remain usable.
+func (m *Mux) Remove(path string, methods ...string) bool {
+	return m.Root().Remove(path, methods...)
+}
+
+//Remove is the same as Mux.Remove except that path is relative to r, and is
+//removed from the tree that r is in. So the routes of a host pattern are
+//removed with the Route returned by Mux.Host.
+func (r *Route) Remove(path string, methods ...string) bool {
+	paths, err := muxpath.ExpandOptionalParts(path)
+	if err != nil {
+		panic(err)
+	}
+	removed := false
+	r.table.update(func(tree *routeTree) error {
+		//Removing finds and prunes nodes anywhere in the tree.
+		tree.ownAll()
+		for _, base := range r.all() {
+			for _, expanded := range paths {
+				if tree.remove(base.host, base.pattern+muxpath.CleanPattern(expanded), methods, r.table.names) {
+					removed = true
+				}
+			}
+		}
+		tree.prune()
+		return nil
+	})
+	return removed
+}
+
+//remove removes the handlers for methods at pattern in the tree of host, and
+//the Routes in names at pattern if it is left without handlers.
+func (tree *routeTree) remove(host, pattern string, methods []string, names map[string]*Route) bool {
+	root := tree.root
+	if len(host) > 0 {
+		if !tree.hasHost(host) {
+			return false
+		}
+		root = tree.rootOf(host)
+	}
+	found := findPattern(root, pattern)
+	if found == nil || !found.isRegistered() {
+		return false
+	}
+
+	found.remove(methods...)
+	if !found.isRegistered() {
+		for name, route := range names {
+			if route.pattern == pattern && route.host == host {
+				delete(names, name)
+			}
+		}
+	}
+	return true
+}
+
+//prune removes empty nodes from tree and merges static nodes that only prefix
+//a single static child into that child.
+func (tree *routeTree) prune() {
+	pruneStatic(tree.root)
+	for _, h := range tree.hosts {
+		pruneStatic(h.root)
+	}
+}
+
+//pruneStatic prunes n's descendants and returns whether n is then empty.
+func pruneStatic(n *staticNode) bool {
+	children := n.staticChildren[:0]
+	for _, child := range n.staticChildren {
+		if pruneStatic(child) {
+			continue
+		}
+		children = append(children, mergeStatic(child))
+	}
+	for i := len(children); i < len(n.staticChildren); i++ {
+		n.staticChildren[i] = nil
+	}
+	n.staticChildren = children
+
+	if n.segmentVarChild != nil && pruneSegmentVar(n.segmentVarChild) {
+		n.segmentVarChild = nil
+	}
+	if n.endVarChild != nil && !n.endVarChild.isRegistered() && n.endVarChild.attributes.isEmpty() {
+		n.endVarChild = nil
+	}
+
+	return !n.isRegistered() && n.attributes.isEmpty() &&
+		len(n.staticChildren) == 0 && n.segmentVarChild == nil && n.endVarChild == nil
+}
+
+//pruneSegmentVar prunes n's descendants and returns whether n is then empty.
+func pruneSegmentVar(n *segmentVarNode) bool {
+	if n.staticChild != nil {
+		if pruneStatic(n.staticChild) {
+			n.staticChild = nil
+		} else {
+			n.staticChild = mergeStatic(n.staticChild)
+		}
+	}
+	return !n.isRegistered() && n.attributes.isEmpty() && n.staticChild == nil
+}
+
+//mergeStatic returns the node that should take n's place in its parent.
+//This is n's only static child prefixed with n's value if n has no handlers and
+//no other children or attributes, and n otherwise.
+func mergeStatic(n *staticNode) *staticNode {
+	if n.isRegistered() || !n.attributes.isEmpty() ||
+		len(n.staticChildren) != 1 || n.segmentVarChild != nil || n.endVarChild != nil {
+		return n
+	}
+	child := n.staticChildren[0]
+	child.value = n.value + child.value
+	return child
+}
@@ -0,0 +1,110 @@
+package httpmux
+
+import (
+	"testing"
+
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+func TestMux_Remove_RemovesHandlersAndAllowsReregistering(t *testing.T) {
+	m := New()
+
+	m.Handle("/users/:id", TestHandler("USER_GET"), "GET")
+	m.Handle("/users/:id", TestHandler("USER_POST"), "POST")
+	m.Handle("/users/:id/posts", TestHandler("POSTS"))
+
+	if !m.Remove("/users/:id", "POST") {
+		t.Fatal("m.Remove() = false WANT true")
+	}
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "POST", Path: "/users/1", Status: 405, Body: "Method Not Allowed\n"},
+		&ServeHTTPTest{Method: "GET", Path: "/users/1", Status: 200, Body: "USER_GET", Variables: []*Variable{{Name: "id", Value: "1", RawValue: "1"}}},
+	)
+
+	m.Remove("/users/:id")
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/users/1", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/users/1/posts", Status: 200, Body: "POSTS", Variables: []*Variable{{Name: "id", Value: "1", RawValue: "1"}}},
+	)
+
+	if _, err := m.TryHandle("/users/:userId", TestHandler("USER")); err == nil {
+		t.Fatal("m.TryHandle() must return an error while /users/:id/posts is registered")
+	}
+
+	m.Remove("/users/:id/posts")
+	if m.Remove("/users/:id/posts") {
+		t.Fatal("m.Remove() = true WANT false")
+	}
+	if _, err := m.TryHandle("/users/:userId", TestHandler("USER")); err != nil {
+		t.Fatal(err)
+	}
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/users/1", Status: 200, Body: "USER", Variables: []*Variable{{Name: "userId", Value: "1", RawValue: "1"}}},
+	)
+}
+
+func TestMux_Remove_MergesStaticNodes(t *testing.T) {
+	m := New()
+
+	group := m.SubRoute("/ab")
+	group.SubRoute("/c").Handle(TestHandler("AB/C"))
+	m.Handle("/abd", TestHandler("ABD"))
+
+	m.Remove("/abd")
+
+	root := m.routes().load().root
+	if len(root.staticChildren) != 1 || root.staticChildren[0].value != "/ab/c" {
+		t.Fatalf("root.staticChildren = %v WANT a single merged /ab/c", root.staticChildren)
+	}
+
+	group.Handle(TestHandler("AB"))
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/ab", Status: 200, Body: "AB"},
+		&ServeHTTPTest{Method: "GET", Path: "/ab/c", Status: 200, Body: "AB/C"},
+		&ServeHTTPTest{Method: "GET", Path: "/abd", Status: 404, Body: NotFoundBody},
+	)
+}
+
+func TestMux_Remove_KeepsVariableSiblingsInOneSegment(t *testing.T) {
+	m := New()
+	m.Handle("/files/:name.:ext", TestHandler("FILE"))
+	m.Handle("/files/:name/meta", TestHandler("META"))
+
+	if !m.Remove("/files/:name/meta") {
+		t.Fatalf("Remove() = false WANT true")
+	}
+	m.Handle("/files/:name/info", TestHandler("INFO"))
+
+	vars := []*Variable{{Name: "name", Value: "a", RawValue: "a"}, {Name: "ext", Value: "pdf", RawValue: "pdf"}}
+	testMux_ServeHTTP(
+		t,
+		m,
+		&ServeHTTPTest{Method: "GET", Path: "/files/a.pdf", Status: 200, Body: "FILE", Variables: vars},
+		&ServeHTTPTest{Method: "GET", Path: "/files/a/meta", Status: 404, Body: NotFoundBody},
+		&ServeHTTPTest{Method: "GET", Path: "/files/a.b/info", Status: 200, Body: "INFO", Variables: []*Variable{{Name: "name", Value: "a.b", RawValue: "a.b"}}},
+	)
+}
+
+func TestMux_Remove_panicsLikeHandleWithUnbalancedBrackets(t *testing.T) {
+	for _, fn := range []func(m *Mux){
+		func(m *Mux) { m.Handle("/docs[/:page", TestHandler("DOCS")) },
+		func(m *Mux) { m.Remove("/docs[/:page") },
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err != muxpath.ErrUnbalancedOptional {
+					t.Errorf("recover() = %v WANT %v", err, muxpath.ErrUnbalancedOptional)
+				}
+			}()
+			fn(New())
+		}()
+	}
+}
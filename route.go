@@ -6,20 +6,66 @@ import (
 	muxpath "github.com/gogolfing/httpmux/path"
 )
 
+//Route is a location in a Mux's routing tree that handlers may be registered on.
+//Route's methods are safe to call concurrently with each other and with the
+//Mux serving requests.
 type Route struct {
-	node
-}
+	//host is the host pattern of the tree that r is in, or empty for the tree
+	//of routes without a host.
+	host     string
+	pattern  string
+	matchers []Matcher
+	table    *routeTable
 
-func newRootRoute() *Route {
-	return newRoute(
-		&staticNode{},
-	)
+	//optionalPattern is the pattern with optional parts that r was created
+	//from, if any. r is then the Route of the first pattern it expands into,
+	//and expansions are the Routes of the others.
+	optionalPattern string
+	expansions      []*Route
 }
 
-func newRoute(n node) *Route {
+func newRoute(host, pattern string, table *routeTable) *Route {
 	return &Route{
-		node: n,
+		host:    host,
+		pattern: pattern,
+		table:   table,
+	}
+}
+
+//withTable returns a copy of r that registers in table.
+func (r *Route) withTable(table *routeTable) *Route {
+	result := *r
+	result.table = table
+	result.expansions = make([]*Route, len(r.expansions))
+	for i, e := range r.expansions {
+		result.expansions[i] = e.withTable(table)
+	}
+	return &result
+}
+
+//Pattern returns the full path pattern, from the root of the Mux, that r was
+//created with.
+func (r *Route) Pattern() string {
+	if len(r.optionalPattern) > 0 {
+		return r.optionalPattern
+	}
+	return r.pattern
+}
+
+//all returns r and the Routes of the other patterns that r's pattern expands
+//into.
+func (r *Route) all() []*Route {
+	if len(r.expansions) == 0 {
+		return []*Route{r}
 	}
+	return append([]*Route{r}, r.expansions...)
+}
+
+//Name registers r under name so that it may be found with Mux.URL.
+//A later call with the same name replaces the previously named Route.
+func (r *Route) Name(name string) *Route {
+	r.table.setName(name, r)
+	return r
 }
 
 func (r *Route) DeleteFunc(handlerFunc http.HandlerFunc) *Route {
@@ -62,52 +108,237 @@ func (r *Route) Put(handler http.Handler) *Route {
 	return r.Handle(handler, http.MethodPut)
 }
 
+//Handle registers handler at r for methods, or for all methods if none are
+//given.
 func (r *Route) Handle(handler http.Handler, methods ...string) *Route {
-	r.node.put(handler, methods...)
+	r.modify(func(route *Route, n node) {
+		n.handlers().putMatching(r.matchers, handler, methods...)
+		n.attrs().pattern = route.pattern
+	})
 	return r
 }
 
+//DisableAutoHead causes HEAD requests found at r not to be served by r's GET
+//handler, even if the Mux has AutoHead set. HEAD is then only allowed if a
+//handler is registered for it.
+func (r *Route) DisableAutoHead() *Route {
+	r.modify(func(_ *Route, n node) {
+		n.handlers().noAutoHead = true
+	})
+	return r
+}
+
+//DisableAutoOptions causes OPTIONS requests found at r not to be answered
+//automatically, even if the Mux has AutoOptions set. OPTIONS is then only
+//allowed if a handler is registered for it.
+func (r *Route) DisableAutoOptions() *Route {
+	r.modify(func(_ *Route, n node) {
+		n.handlers().noAutoOptions = true
+	})
+	return r
+}
+
+//WithMeta sets the metadata value for key on r, e.g. the owning team or the
+//authorization scope of r, replacing any previous value for key.
+//Metadata is available from RouteFrom for requests found at r and at the
+//Routes beneath r, in the same sense as for Route.Use. A value set on a Route
+//takes precedence over values for the same key set on the Routes it is
+//beneath.
+func (r *Route) WithMeta(key string, value interface{}) *Route {
+	r.modify(func(route *Route, n node) {
+		attrs := n.attrs()
+		ownDescendants(n, attrs.gen)
+		meta := make(map[string]interface{}, len(attrs.meta)+1)
+		for k, v := range attrs.meta {
+			meta[k] = v
+		}
+		meta[key] = value
+		attrs.meta = meta
+		attrs.pattern = route.pattern
+	})
+	return r
+}
+
+//modify calls fn with each of r.all() and its node in a copy of the tree that
+//is then published. It panics if a node cannot be added to the tree.
+func (r *Route) modify(fn func(route *Route, n node)) {
+	err := r.table.update(func(tree *routeTree) error {
+		for _, route := range r.all() {
+			n, err := route.appendTo(tree)
+			if err != nil {
+				return err
+			}
+			fn(route, n)
+		}
+		return nil
+	})
+	panicIfInvalid(err)
+}
+
+//Meta returns the metadata value for key on r and whether it is set, including
+//values set on the Routes r is beneath.
+func (r *Route) Meta(key string) (interface{}, bool) {
+	n := r.lookup(r.table.load())
+	if n == nil {
+		return nil, false
+	}
+	value, ok := n.attrs().allMeta[key]
+	return value, ok
+}
+
+//SubRoute returns the Route at path relative to r.
+//If path cannot be registered, then SubRoute panics with the underlying error
+//of the *ErrInvalidRoute that TrySubRoute returns, e.g. an *ErrUnequalVars.
+//
+//path may have optional parts enclosed in brackets, as in
+//"/docs[/:page[/:section]]", see muxpath.ExpandOptionalParts. The returned
+//Route then registers handlers and middleware at every path it expands into,
+//and the variables of omitted parts are absent from requests found at the
+//shorter paths.
 func (r *Route) SubRoute(path string) *Route {
-	resultNode := r.node
-	var err error = nil
+	result, err := r.TrySubRoute(path)
+	panicIfInvalid(err)
+	return result
+}
 
-	path = muxpath.Clean(path)
-	parts := muxpath.SplitIntoStaticAndVariableParts(path)
+//TrySubRoute is the same as SubRoute except that an *ErrInvalidRoute is
+//returned instead of panicking when path cannot be registered.
+//The routing tree is unchanged if an error is returned.
+func (r *Route) TrySubRoute(path string) (*Route, error) {
+	paths, err := muxpath.ExpandOptionalParts(path)
+	if err != nil {
+		return nil, &ErrInvalidRoute{Pattern: r.Pattern() + muxpath.CleanPattern(path), Err: err}
+	}
+
+	routes := []*Route{}
+	err = r.table.update(func(tree *routeTree) error {
+		for _, base := range r.all() {
+			for _, expanded := range paths {
+				route := newRoute(base.host, base.pattern+muxpath.CleanPattern(expanded), base.table)
+				if _, err := route.appendTo(tree); err != nil {
+					return err
+				}
+				routes = append(routes, route)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(routes) == 1 {
+		if routes[0].pattern == r.pattern {
+			return r, nil
+		}
+		return routes[0], nil
+	}
+
+	result := routes[0]
+	result.optionalPattern = r.Pattern() + muxpath.CleanPattern(path)
+	result.expansions = routes[1:]
+	return result, nil
+}
+
+//panicIfInvalid panics with the underlying error of err if err is an
+//*ErrInvalidRoute, and with err otherwise, if it is not nil.
+func panicIfInvalid(err error) {
+	if invalid, ok := err.(*ErrInvalidRoute); ok {
+		panic(invalid.Err)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+//appendTo returns r's node in tree, which is appended to tree if it is not
+//already there.
+func (r *Route) appendTo(tree *routeTree) (node, error) {
+	root := tree.rootOf(r.host)
+	n, conflict, err := appendPath(root, r.pattern)
+	if err != nil {
+		return nil, newErrInvalidRoute(root, r.pattern, conflict, err)
+	}
+	return n, nil
+}
+
+//lookup returns r's node in tree, or nil if it is not there.
+func (r *Route) lookup(tree *routeTree) node {
+	var root node
+	if len(r.host) == 0 {
+		root = tree.root
+	}
+	for _, h := range tree.hosts {
+		if h.pattern == r.host {
+			root = h.root
+		}
+	}
+	if root == nil {
+		return nil
+	}
+	return findPattern(root, r.pattern)
+}
+
+//appendPath appends the parts of path to start and returns the node at the end
+//of path. If an error is returned, then conflict is the existing node that path
+//conflicts with if there is one.
+func appendPath(start node, path string) (result, conflict node, err error) {
+	parts, err := muxpath.SplitPattern(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	result = start
 	for _, part := range parts {
-		name, ok := muxpath.ExtractVariableName(part)
+		var next node
+
+		name, constraintSource, ok := muxpath.ExtractVariableNameAndConstraint(part)
 		if ok {
-			switch {
-			case muxpath.IsSegmentVariable(part):
-				resultNode, err = resultNode.appendSegmentVar(VarName(name))
-			case muxpath.IsEndVariable(part):
-				resultNode, err = resultNode.appendEndVar(VarName(name))
+			var c *constraint
+			c, err = newConstraint(VarName(name), constraintSource)
+			if err == nil {
+				switch {
+				case muxpath.IsSegmentVariable(part):
+					next, err = result.appendSegmentVar(VarName(name), c)
+				case muxpath.IsEndVariable(part):
+					next, err = result.appendEndVar(VarName(name), c)
+				}
 			}
 		} else {
-			resultNode, err = resultNode.appendStatic(part)
+			next, err = result.appendStatic(part)
 		}
 
 		if err != nil {
-			panic(err)
+			return nil, next, err
 		}
+		result = next
 	}
+	return result, nil, nil
+}
 
-	if resultNode == r.node {
-		return r
+//newErrInvalidRoute returns the error for pattern conflicting with the node
+//conflict, if it is not nil, in the tree rooted at root.
+func newErrInvalidRoute(root node, pattern string, conflict node, err error) *ErrInvalidRoute {
+	result := &ErrInvalidRoute{
+		Pattern: pattern,
+		Err:     err,
 	}
-
-	return newRoute(resultNode)
+	if conflict != nil {
+		result.ConflictingPattern = firstRegisteredPattern(root, "", conflict)
+	}
+	return result
 }
 
-func (r *Route) findHandler(req *http.Request, m foundMatcher) (http.Handler, []*Variable, error) {
-	found, vars := r.node.find(muxpath.Clean(req.URL.Path), m)
+//findHandler returns the handler for req at path in the tree rooted at root.
+//The variables and middleware of the node that path was found at are added to
+//mt, which is reset if path is not found.
+func findHandler(root node, req *http.Request, path string, m foundMatcher, o methodOptions, mt *match) (http.Handler, error) {
+	found := root.find(muxpath.Clean(path), m, mt)
 
 	if found == nil {
-		return nil, nil, ErrNotFound
+		return nil, ErrNotFound
 	}
 
-	handler, err := found.get(req.Method)
-	if err != nil {
-		return nil, nil, err
-	}
-	return handler, vars, nil
+	attrs := found.attrs()
+	mt.pattern, mt.meta, mt.middleware = attrs.pattern, attrs.allMeta, attrs.chain
+	return found.handlers().getWithOptions(req, o)
 }
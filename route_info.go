@@ -0,0 +1,119 @@
+package httpmux
+
+import "net/http"
+
+//RouteInfo describes a Route that has handlers registered on it.
+type RouteInfo struct {
+	//Host is the host pattern the Route was registered with using Mux.Host, or
+	//empty if there is none.
+	Host string
+
+	//Pattern is the full pattern of the Route.
+	Pattern string
+
+	//Methods are the sorted methods that have handlers registered.
+	Methods []string
+
+	//Handlers are the handlers registered without Matchers for each of
+	//Methods. A method is in Methods but not in Handlers if it only has
+	//handlers registered with Matchers.
+	Handlers map[string]http.Handler
+
+	//AllMethods is the handler registered without Matchers for all methods, or
+	//nil if there is none.
+	AllMethods http.Handler
+
+	//Matched are the handlers registered with Matchers, in the order they are
+	//tried for each method.
+	Matched []MatchedHandler
+
+	//Meta is the metadata of the Route, see Route.WithMeta. It must not be
+	//modified.
+	Meta map[string]interface{}
+}
+
+//MatchedHandler is a handler registered with Matchers using Route.Match.
+type MatchedHandler struct {
+	//Method is the method Handler is registered for, or empty if it is
+	//registered for all methods.
+	Method string
+
+	//Matchers are the Matchers a request must satisfy to be served by Handler.
+	Matchers []Matcher
+
+	Handler http.Handler
+}
+
+//HandlesAllMethods returns whether info has a handler registered for all
+//methods, with or without Matchers.
+func (info RouteInfo) HandlesAllMethods() bool {
+	if info.AllMethods != nil {
+		return true
+	}
+	for _, matched := range info.Matched {
+		if len(matched.Method) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//Walk calls fn with a RouteInfo for each Route registered on m, in the order
+//that they are searched for while serving.
+//Walk stops and returns the first non-nil error returned by fn.
+//
+//The RouteInfos are collected before fn is first called, so fn may register
+//routes on m.
+func (m *Mux) Walk(fn func(info RouteInfo) error) error {
+	for _, info := range m.routes().load().routeInfos() {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tree *routeTree) routeInfos() []RouteInfo {
+	result := []RouteInfo{}
+	appendInfos := func(host string, root node) {
+		walkNodes(root, "", func(n node, pattern string) bool {
+			if n.isRegistered() {
+				result = append(result, newRouteInfo(host, pattern, n))
+			}
+			return true
+		})
+	}
+	for _, h := range tree.hosts {
+		appendInfos(h.pattern, h.root)
+	}
+	appendInfos("", tree.root)
+	return result
+}
+
+func newRouteInfo(host, pattern string, n node) RouteInfo {
+	mh := n.handlers()
+	result := RouteInfo{
+		Host:     host,
+		Pattern:  pattern,
+		Methods:  mh.listMethods(),
+		Handlers: make(map[string]http.Handler, len(mh.methods)),
+		Meta:     n.attrs().allMeta,
+	}
+	result.AllMethods = result.appendMatched("", mh.all)
+	for _, method := range result.Methods {
+		if handler := result.appendMatched(method, mh.methods[method]); handler != nil {
+			result.Handlers[method] = handler
+		}
+	}
+	return result
+}
+
+//appendMatched appends the handlers registered with Matchers in handler to
+//info.Matched and returns the handler registered without Matchers.
+func (info *RouteInfo) appendMatched(method string, handler http.Handler) http.Handler {
+	fallback, alternatives := unwrapHandler(handler)
+	for _, alt := range alternatives {
+		info.Matched = append(info.Matched, MatchedHandler{Method: method, Matchers: alt.matchers, Handler: alt.handler})
+	}
+	return fallback
+}
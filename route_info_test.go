@@ -0,0 +1,99 @@
+package httpmux
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMux_Walk(t *testing.T) {
+	m := New()
+
+	m.Handle("/", TestHandler("ROOT"))
+	m.Handle("/users", TestHandler("USERS_GET"), "GET")
+	m.Handle("/users", TestHandler("USERS_POST"), "post")
+	m.Handle("/users/:id{int}", TestHandler("USER"))
+	m.Handle("/users/:id{int}/posts", TestHandler("POSTS_GET"), "GET")
+	m.Handle("/users/:id{int}/posts", TestHandler("POSTS_ALL"))
+	m.Handle("/files/*file", TestHandler("FILE"), "GET")
+	m.Handle("/colon::", TestHandler("COLON"))
+	m.SubRoute("/unregistered")
+
+	want := []RouteInfo{
+		{"", "/", []string{}, map[string]http.Handler{}, TestHandler("ROOT"), nil, nil},
+		{"", "/colon::", []string{}, map[string]http.Handler{}, TestHandler("COLON"), nil, nil},
+		{"", "/files/*file", []string{"GET"}, map[string]http.Handler{"GET": TestHandler("FILE")}, nil, nil, nil},
+		{
+			"",
+			"/users",
+			[]string{"GET", "POST"},
+			map[string]http.Handler{"GET": TestHandler("USERS_GET"), "POST": TestHandler("USERS_POST")},
+			nil,
+			nil,
+			nil,
+		},
+		{"", "/users/:id{int}", []string{}, map[string]http.Handler{}, TestHandler("USER"), nil, nil},
+		{"", "/users/:id{int}/posts", []string{"GET"}, map[string]http.Handler{"GET": TestHandler("POSTS_GET")}, TestHandler("POSTS_ALL"), nil, nil},
+	}
+
+	result := []RouteInfo{}
+	err := m.Walk(func(info RouteInfo) error {
+		result = append(result, info)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(result, want) {
+		t.Errorf("m.Walk() = %v, %v WANT %v, nil", result, err, want)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = m.Walk(func(info RouteInfo) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("m.Walk() = %v after %v calls WANT %v after 1 call", err, count, stop)
+	}
+}
+
+func TestMux_Walk_ReportsMeta(t *testing.T) {
+	m := New()
+	m.Handle("/users", TestHandler("USERS")).WithMeta("team", "identity")
+
+	var meta map[string]interface{}
+	m.Walk(func(info RouteInfo) error {
+		meta = info.Meta
+		return nil
+	})
+	if want := map[string]interface{}{"team": "identity"}; !reflect.DeepEqual(meta, want) {
+		t.Errorf("info.Meta = %v WANT %v", meta, want)
+	}
+}
+
+func TestMux_Walk_ReportsHandlersRegisteredWithMatchers(t *testing.T) {
+	m := New()
+	accept := Accept("application/json")
+	items := m.SubRoute("/items")
+	items.Get(TestHandler("HTML"))
+	items.Match(accept).Get(TestHandler("JSON"))
+	items.Match(accept).Handle(TestHandler("ALL_JSON"))
+
+	var info RouteInfo
+	m.Walk(func(i RouteInfo) error {
+		info = i
+		return nil
+	})
+
+	wantHandlers := map[string]http.Handler{"GET": TestHandler("HTML")}
+	wantMatched := []MatchedHandler{
+		{"", []Matcher{accept}, TestHandler("ALL_JSON")},
+		{"GET", []Matcher{accept}, TestHandler("JSON")},
+	}
+	if !reflect.DeepEqual(info.Handlers, wantHandlers) || info.AllMethods != nil || !reflect.DeepEqual(info.Matched, wantMatched) {
+		t.Errorf("info = %v WANT Handlers %v, AllMethods nil, Matched %v", info, wantHandlers, wantMatched)
+	}
+	if !info.HandlesAllMethods() {
+		t.Errorf("info.HandlesAllMethods() = false WANT true")
+	}
+}
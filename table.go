@@ -0,0 +1,148 @@
+package httpmux
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+//routeTable holds the routing tree that a Mux serves.
+//
+//A published routeTree is never modified. Registration copies the nodes of
+//the current tree that it modifies, along with the paths to them, while holding
+//lock, and then publishes the resulting tree, which shares its other nodes with
+//the current one. So requests are searched for without locking, and
+//registering a route only copies its path.
+type routeTable struct {
+	lock sync.Mutex
+	tree atomic.Value //*routeTree
+
+	//mux is the Mux that serves the table, whose settings affect building
+	//URLs.
+	mux *Mux
+
+	root      *Route
+	hostRoots map[string]*Route
+
+	//names are the named Routes, see Route.Name. They are guarded by lock.
+	names map[string]*Route
+}
+
+//routeTree is the tree of nodes, and the Routes named within it, at one point
+//in time.
+type routeTree struct {
+	//gen is the generation of the tree, see attributes.gen.
+	gen uint64
+
+	root  *staticNode
+	hosts []*hostRoute
+}
+
+func newRouteTable(mux *Mux) *routeTable {
+	return newRouteTableWith(mux, &routeTree{root: &staticNode{}})
+}
+
+func newRouteTableWith(mux *Mux, tree *routeTree) *routeTable {
+	t := &routeTable{
+		mux:       mux,
+		hostRoots: map[string]*Route{},
+		names:     map[string]*Route{},
+	}
+	t.root = newRoute("", "", t)
+	t.tree.Store(tree)
+	return t
+}
+
+//load returns the currently published tree of t. It must not be modified.
+func (t *routeTable) load() *routeTree {
+	return t.tree.Load().(*routeTree)
+}
+
+//update calls fn with a copy of the current tree and publishes the copy if fn
+//returns nil. The current tree is unchanged if fn returns an error. fn may only
+//modify the nodes that the copy owns, see routeTree.clone.
+func (t *routeTable) update(fn func(tree *routeTree) error) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tree := t.load().clone()
+	if err := fn(tree); err != nil {
+		return err
+	}
+	tree.setInherited()
+	t.tree.Store(tree)
+	return nil
+}
+
+//copy returns a new routeTable served by mux with the current tree of t, whose
+//later changes do not affect t and are not affected by t.
+func (t *routeTable) copy(mux *Mux) *routeTable {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := newRouteTableWith(mux, t.load())
+	for name, route := range t.names {
+		result.names[name] = route.withTable(result)
+	}
+	return result
+}
+
+//escapedPath returns whether the Mux serving t has UseEscapedPath set.
+func (t *routeTable) escapedPath() bool {
+	return t.mux != nil && t.mux.UseEscapedPath
+}
+
+func (t *routeTable) namedRoute(name string) (*Route, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	route, ok := t.names[name]
+	return route, ok
+}
+
+func (t *routeTable) setName(name string, route *Route) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.names[name] = route
+}
+
+//rootOf returns the root of the tree of the host pattern host, or of the tree
+//of routes without a host if host is empty. The host's tree is added if it is
+//not in tree.
+func (tree *routeTree) rootOf(host string) *staticNode {
+	if len(host) == 0 {
+		return tree.root
+	}
+	for _, h := range tree.hosts {
+		if h.pattern == host {
+			return h.root
+		}
+	}
+	h := newHostRoute(host)
+	h.root.gen = tree.gen
+	tree.hosts = append(tree.hosts, h)
+	return h.root
+}
+
+//findHandler searches the trees of the host patterns that match req's host in
+//the order they were added, and then the tree of routes without a host.
+func (tree *routeTree) findHandler(req *http.Request, path string, m foundMatcher, o methodOptions, mt *match) (http.Handler, error) {
+	for _, h := range tree.hosts {
+		mt.traceBegin(TraceHost, h.pattern, req.Host)
+		if !h.matches(req.Host, mt) {
+			mt.traceEnd(nil, TraceNoMatch)
+			continue
+		}
+		handler, err := findHandler(h.root, req, path, m, o, mt)
+		if err != ErrNotFound {
+			mt.traceEnd(nil, TraceFoundBeneath)
+			mt.host = h.pattern
+			return handler, err
+		}
+		mt.traceEnd(nil, TraceNotFoundBeneath)
+		mt.reset(matchMark{})
+	}
+
+	return findHandler(tree.root, req, path, m, o, mt)
+}
@@ -0,0 +1,166 @@
+package httpmux
+
+import (
+	"net/http"
+	"strconv"
+
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//Kinds of TraceSteps.
+const (
+	TraceHost            = "host"
+	TraceStatic          = "static"
+	TraceSegmentVariable = "segment variable"
+	TraceEndVariable     = "end variable"
+)
+
+//Results of TraceSteps.
+const (
+	TraceFound                  = "found"
+	TraceFoundBeneath           = "found beneath"
+	TraceNoMatch                = "no match"
+	TraceNotFoundBeneath        = "not found beneath"
+	TraceConstraintNotSatisfied = "constraint not satisfied"
+	TraceSkippedEmptyValue      = "skipped empty value"
+)
+
+//TraceStep is a single step of searching for a request's Route, see
+//Mux.Explain.
+type TraceStep struct {
+	//Depth is the number of steps that this step is nested within.
+	Depth int
+
+	//Kind is one of TraceHost, TraceStatic, TraceSegmentVariable, or
+	//TraceEndVariable.
+	Kind string
+
+	//Value is the static value or variable pattern of the node searched, or the
+	//host pattern for TraceHost steps.
+	Value string
+
+	//Remaining is the path that remains to be found at the node, or the
+	//request's host for TraceHost steps.
+	Remaining string
+
+	//Captured is the value tried for variables.
+	Captured string
+
+	//Result is one of the Trace results, e.g. TraceFound or TraceNoMatch.
+	Result string
+}
+
+//tracer records the steps of a search. It is only set on matches created by
+//Mux.Explain.
+type tracer struct {
+	steps []TraceStep
+
+	//open are the indexes of the steps that have begun but not ended.
+	open []int
+}
+
+//traceBegin records that the search of a node, described by kind and value,
+//for remaining has begun. Every traceBegin must be followed by a traceEnd.
+func (mt *match) traceBegin(kind, value, remaining string) {
+	if mt.trace == nil {
+		return
+	}
+	t := mt.trace
+	t.open = append(t.open, len(t.steps))
+	t.steps = append(t.steps, TraceStep{Depth: len(t.open) - 1, Kind: kind, Value: value, Remaining: remaining})
+}
+
+//traceNode calls traceBegin with the kind and value of n.
+func (mt *match) traceNode(n node, remaining string) {
+	if mt.trace == nil {
+		return
+	}
+	switch n := n.(type) {
+	case *staticNode:
+		mt.traceBegin(TraceStatic, n.value, remaining)
+	case *segmentVarNode:
+		mt.traceBegin(TraceSegmentVariable, variablePattern(muxpath.SegmentVarRune, n.name, n.constraint), remaining)
+	case *endVarNode:
+		mt.traceBegin(TraceEndVariable, variablePattern(muxpath.EndVarRune, n.name, n.constraint), remaining)
+	}
+}
+
+//traceCapture records the value tried for the variable of the last step.
+func (mt *match) traceCapture(value string) {
+	if mt.trace == nil {
+		return
+	}
+	mt.trace.steps[len(mt.trace.steps)-1].Captured = value
+}
+
+//traceEnd ends the last step that has begun with result and returns found.
+func (mt *match) traceEnd(found node, result string) node {
+	if mt.trace == nil {
+		return found
+	}
+	t := mt.trace
+	t.steps[t.open[len(t.open)-1]].Result = result
+	t.open = t.open[:len(t.open)-1]
+	return found
+}
+
+//Explanation describes how a request is searched for, see Mux.Explain.
+type Explanation struct {
+	//Path is the path of the request that is searched for.
+	Path string
+
+	//Steps are the steps of the search in the order they are taken.
+	Steps []TraceStep
+
+	//Route is the Route the request was found at. It is only set if Err is not
+	//ErrNotFound.
+	Route MatchedRoute
+
+	//Variables are the Variables found for the request.
+	Variables []*Variable
+
+	//Redirect is the path the request would be redirected to, or empty if it
+	//would not be redirected.
+	Redirect string
+
+	//RedirectStatus is the status code the request would be redirected with, or
+	//0 if it would not be redirected.
+	RedirectStatus int
+
+	//Err is nil if the request would be served by a handler, and otherwise the
+	//error that would be served, e.g. ErrNotFound or an ErrMethodNotAllowed.
+	Err error
+}
+
+//Explain searches for r the same way that ServeHTTP does without serving it,
+//and returns an Explanation of each step of the search.
+func (m *Mux) Explain(r *http.Request) *Explanation {
+	mt := &match{trace: &tracer{}}
+	d := m.decide(r, mt)
+	result := &Explanation{
+		Path:           d.path,
+		Steps:          mt.trace.steps,
+		Redirect:       d.redirect,
+		RedirectStatus: d.redirectStatus,
+		Err:            d.err,
+	}
+	if !d.matched {
+		return result
+	}
+
+	result.Route = MatchedRoute{Host: mt.host, Pattern: mt.pattern, Meta: mt.meta}
+	for i := range mt.vars {
+		v := mt.vars[i]
+		result.Variables = append(result.Variables, &v)
+	}
+	return result
+}
+
+//String returns a description of s on a single line.
+func (s TraceStep) String() string {
+	result := s.Kind + " " + strconv.Quote(s.Value) + " at " + strconv.Quote(s.Remaining)
+	if len(s.Captured) > 0 || s.Kind == TraceSegmentVariable || s.Kind == TraceEndVariable {
+		result += " with value " + strconv.Quote(s.Captured)
+	}
+	return result + ": " + s.Result
+}
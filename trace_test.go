@@ -0,0 +1,90 @@
+package httpmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMux_Explain(t *testing.T) {
+	m := New()
+	m.Handle("/users/me", TestHandler("ME"), "GET")
+	m.Handle("/users/:id{int}", TestHandler("USER"), "GET", "PUT")
+	m.Handle("/files/*path", TestHandler("FILES"))
+	m.Host("api.example.com").SubRoute("/status").Handle(TestHandler("STATUS"))
+
+	e := m.Explain(httptest.NewRequest("DELETE", "/users/12", nil))
+	if e.Err == nil || e.Err.Error() != ErrMethodNotAllowed(nil).Error() {
+		t.Errorf("Explain().Err = %v WANT ErrMethodNotAllowed", e.Err)
+	}
+	if e.Route.Pattern != "/users/:id{int}" {
+		t.Errorf("Explain().Route.Pattern = %q", e.Route.Pattern)
+	}
+	if len(e.Variables) != 1 || e.Variables[0].Value != "12" {
+		t.Errorf("Explain().Variables = %v", e.Variables)
+	}
+
+	e = m.Explain(httptest.NewRequest("GET", "http://api.example.com/users/abc", nil))
+	if e.Err != ErrNotFound {
+		t.Errorf("Explain().Err = %v WANT %v", e.Err, ErrNotFound)
+	}
+	want := []TraceStep{
+		{Depth: 0, Kind: TraceHost, Value: "api.example.com", Remaining: "api.example.com", Result: TraceNotFoundBeneath},
+		{Depth: 1, Kind: TraceStatic, Value: "", Remaining: "/users/abc", Result: TraceNotFoundBeneath},
+		{Depth: 2, Kind: TraceStatic, Value: "/status", Remaining: "/users/abc", Result: TraceNoMatch},
+		{Depth: 0, Kind: TraceStatic, Value: "", Remaining: "/users/abc", Result: TraceNotFoundBeneath},
+		{Depth: 1, Kind: TraceStatic, Value: "/", Remaining: "/users/abc", Result: TraceNotFoundBeneath},
+		{Depth: 2, Kind: TraceStatic, Value: "users/", Remaining: "users/abc", Result: TraceNotFoundBeneath},
+		{
+			Depth:     3,
+			Kind:      TraceSegmentVariable,
+			Value:     ":id{int}",
+			Remaining: "abc",
+			Captured:  "abc",
+			Result:    TraceConstraintNotSatisfied,
+		},
+	}
+	if !reflect.DeepEqual(e.Steps, want) {
+		t.Errorf("Explain().Steps = %v WANT %v", e.Steps, want)
+	}
+
+	e = m.Explain(httptest.NewRequest("GET", "/files/a/b", nil))
+	if last := e.Steps[len(e.Steps)-1]; last.String() != `end variable "*path" at "a/b" with value "a/b": found` {
+		t.Errorf("last step = %q", last.String())
+	}
+}
+
+func TestMux_Explain_redirects(t *testing.T) {
+	m := New()
+	m.RedirectTrailingSlash = true
+	m.RedirectCleanPath = true
+	m.Handle("/users/", TestHandler("USERS"))
+
+	tests := []struct {
+		method   string
+		path     string
+		redirect string
+		status   int
+	}{
+		{"GET", "/users/", "", 0},
+		{"GET", "/users", "/users/", http.StatusMovedPermanently},
+		{"POST", "/users", "/users/", http.StatusPermanentRedirect},
+		{"GET", "/a/../users/", "/users/", http.StatusMovedPermanently},
+		{"PUT", "/a/../users/", "/users/", http.StatusPermanentRedirect},
+	}
+	for _, test := range tests {
+		e := m.Explain(httptest.NewRequest(test.method, test.path, nil))
+		if e.Redirect != test.redirect || e.RedirectStatus != test.status {
+			t.Errorf("Explain(%v %q) = %q, %v WANT %q, %v", test.method, test.path, e.Redirect, e.RedirectStatus, test.redirect, test.status)
+		}
+		if test.status == 0 {
+			continue
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != e.RedirectStatus {
+			t.Errorf("ServeHTTP(%v %q) = %v WANT %v", test.method, test.path, w.Code, e.RedirectStatus)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package httpmux
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//URL returns the escaped path of r with each variable in r's pattern replaced
+//by the Value of the Variable in vars with the same Name.
+//An ErrMissingVariable is returned if a variable in the pattern does not have
+//a corresponding Variable in vars, and an *ErrUnsatisfiedConstraint if the
+//Value of a Variable does not satisfy the constraint of its variable.
+//
+//Static parts of r's pattern are escaped unless the Mux serving r has
+//UseEscapedPath set, in which case they are registered escaped already.
+//
+//If r's pattern has optional parts, then the path is built from the pattern
+//it expands into with the most variables that all have a Variable in vars.
+//
+//If r is beneath a host pattern, see Mux.Host, then the result is the
+//scheme-relative URL "//" + host + path, where host is the host pattern with
+//each variable replaced by the Value of the Variable in vars with the same
+//Name.
+func (r *Route) URL(vars ...*Variable) (string, error) {
+	path, err := r.path(vars)
+	if err != nil || len(r.host) == 0 {
+		return path, err
+	}
+	host, err := buildHost(r.host, vars)
+	if err != nil {
+		return "", err
+	}
+	return "//" + host + path, nil
+}
+
+func (r *Route) path(vars []*Variable) (string, error) {
+	result, err := buildPath(r.pattern, vars, r.table.escapedPath())
+	if err != nil {
+		return "", err
+	}
+	most := countVariables(r.pattern)
+	for _, e := range r.expansions {
+		count := countVariables(e.pattern)
+		if count <= most {
+			continue
+		}
+		if path, err := buildPath(e.pattern, vars, r.table.escapedPath()); err == nil {
+			result, most = path, count
+		}
+	}
+	return result, nil
+}
+
+func countVariables(pattern string) int {
+	count := 0
+	parts, _ := muxpath.SplitPattern(pattern)
+	for _, part := range parts {
+		if _, ok := muxpath.ExtractVariableName(part); ok {
+			count++
+		}
+	}
+	return count
+}
+
+//buildPath builds a path from pattern and vars. Static parts of pattern are
+//escaped unless escaped is true.
+func buildPath(pattern string, vars []*Variable, escaped bool) (string, error) {
+	parts, err := muxpath.SplitPattern(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	for _, part := range parts {
+		name, constraintSource, ok := muxpath.ExtractVariableNameAndConstraint(part)
+		if !ok {
+			if !escaped {
+				part = escapeStatic(part)
+			}
+			buf.WriteString(part)
+			continue
+		}
+
+		v := findVariable(vars, VarName(name))
+		if v == nil {
+			return "", ErrMissingVariable(name)
+		}
+		c, err := newConstraint(v.Name, constraintSource)
+		if err != nil {
+			return "", err
+		}
+		if !c.match(v.Value) {
+			return "", &ErrUnsatisfiedConstraint{Variable: v.Name, Constraint: c.String(), Value: v.Value}
+		}
+
+		switch {
+		case muxpath.IsSegmentVariable(part):
+			//segment variables starting after a slash must not be empty.
+			if len(v.Value) == 0 && bytes.HasSuffix(buf.Bytes(), []byte(muxpath.Slash)) {
+				return "", ErrMissingVariable(name)
+			}
+			buf.WriteString(url.PathEscape(v.Value))
+		case muxpath.IsEndVariable(part):
+			buf.WriteString(escapeEndValue(v.Value))
+		}
+	}
+
+	return muxpath.EnsureRootSlash(buf.String()), nil
+}
+
+func buildHost(pattern string, vars []*Variable) (string, error) {
+	labels := strings.Split(pattern, hostSeparator)
+	for i, label := range labels {
+		name, ok := extractHostVarName(label)
+		if !ok {
+			continue
+		}
+		v := findVariable(vars, VarName(name))
+		if v == nil || len(v.Value) == 0 {
+			return "", ErrMissingVariable(name)
+		}
+		labels[i] = v.Value
+	}
+	return strings.Join(labels, hostSeparator), nil
+}
+
+func findVariable(vars []*Variable, name VarName) *Variable {
+	for _, v := range vars {
+		if v != nil && v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func escapeStatic(static string) string {
+	return (&url.URL{Path: static}).EscapedPath()
+}
+
+//escapeEndValue escapes each slash separated segment of value. value is
+//returned without allocating if none of them need escaping.
+func escapeEndValue(value string) string {
+	if !needsEndValueEscape(value) {
+		return value
+	}
+	segments := strings.Split(value, muxpath.Slash)
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, muxpath.Slash)
+}
+
+func needsEndValueEscape(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if c := value[i : i+1]; c != muxpath.Slash && url.PathEscape(c) != c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package httpmux
+
+import "testing"
+
+func TestMux_URL(t *testing.T) {
+	m := New()
+
+	m.Handle("/", TestHandler("ROOT")).Name("root")
+	users := m.SubRoute("/users").Name("users")
+	users.SubRoute("/:id").Name("user")
+	users.SubRoute("/:id/files/*file").Name("file")
+	m.SubRoute("/static with space").Name("space")
+	m.SubRoute("/tags/:slug{[a-z-]+}").Name("tag")
+
+	tests := []struct {
+		name string
+		vars []*Variable
+
+		result string
+		err    error
+	}{
+		{"root", nil, "/", nil},
+		{"users", nil, "/users", nil},
+		{"user", []*Variable{{Name: "id", Value: "123"}}, "/users/123", nil},
+		{"user", []*Variable{{Name: "other", Value: "1"}, {Name: "id", Value: "a/b c"}}, "/users/a%2Fb%20c", nil},
+		{"user", nil, "", ErrMissingVariable("id")},
+		{"user", []*Variable{{Name: "id", Value: ""}}, "", ErrMissingVariable("id")},
+		{"file", []*Variable{{Name: "id", Value: "1"}, {Name: "file", Value: "dir/a b.txt"}}, "/users/1/files/dir/a%20b.txt", nil},
+		{"file", []*Variable{{Name: "id", Value: "1"}, {Name: "file", Value: ""}}, "/users/1/files/", nil},
+		{"file", []*Variable{{Name: "id", Value: "1"}}, "", ErrMissingVariable("file")},
+		{"space", nil, "/static%20with%20space", nil},
+		{"tag", []*Variable{{Name: "slug", Value: "go-lang"}}, "/tags/go-lang", nil},
+		{"unknown", nil, "", ErrUnknownRouteName("unknown")},
+	}
+
+	for i, test := range tests {
+		result, err := m.URL(test.name, test.vars...)
+		if result != test.result || err != test.err {
+			t.Errorf("%v: m.URL(%q) = %q, %v WANT %q, %v", i, test.name, result, err, test.result, test.err)
+		}
+	}
+}
+
+func TestRoute_URL_returnsErrorsForValuesNotSatisfyingConstraints(t *testing.T) {
+	m := New()
+
+	id := m.SubRoute("/users/:id{int}")
+	file := m.SubRoute("/files/*file{[a-z]+/\\.\\./[a-z]+}")
+
+	tests := []struct {
+		route *Route
+		value string
+
+		result string
+		ok     bool
+	}{
+		{id, "12", "/users/12", true},
+		{id, "twelve", "", false},
+		{file, "a/../b", "/files/a/../b", true},
+		{file, "a/b", "", false},
+	}
+
+	for i, test := range tests {
+		result, err := test.route.URL(&Variable{Name: "id", Value: test.value}, &Variable{Name: "file", Value: test.value})
+		if result != test.result || (err == nil) != test.ok {
+			t.Errorf("%v: URL() = %q, %v WANT %q, %v", i, result, err, test.result, test.ok)
+		}
+		if _, ok := err.(*ErrUnsatisfiedConstraint); err != nil && !ok {
+			t.Errorf("%v: URL() error = %T WANT *ErrUnsatisfiedConstraint", i, err)
+		}
+	}
+}
+
+func TestRoute_URL_matchesRegisteredRoute(t *testing.T) {
+	m := New()
+
+	route := m.SubRoute("/a/:b/c").SubRoute("/*d").Handle(TestHandler("D"))
+
+	path, err := route.URL(&Variable{Name: "b", Value: "bee"}, &Variable{Name: "d", Value: "dee/eee"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testMux_ServeHTTP(t, m, &ServeHTTPTest{
+		Method: "GET",
+		Path:   path,
+		Status: 200,
+		Body:   "D",
+		Variables: []*Variable{
+			{Name: "b", Value: "bee", RawValue: "bee"},
+			{Name: "d", Value: "dee/eee", RawValue: "dee/eee"},
+		},
+	})
+}
+
+func TestRoute_URL_usesTheLongestExpansionOfOptionalParts(t *testing.T) {
+	m := New()
+
+	m.SubRoute("/docs[/:page[/:section]]").Name("docs")
+	m.SubRoute("/users/:id[/edit]").Name("user")
+
+	tests := []struct {
+		name string
+		vars []*Variable
+
+		result string
+		err    error
+	}{
+		{"docs", nil, "/docs", nil},
+		{"docs", []*Variable{{Name: "page", Value: "intro"}}, "/docs/intro", nil},
+		{"docs", []*Variable{{Name: "page", Value: "intro"}, {Name: "section", Value: "a"}}, "/docs/intro/a", nil},
+		{"docs", []*Variable{{Name: "section", Value: "a"}}, "/docs", nil},
+		{"user", []*Variable{{Name: "id", Value: "1"}}, "/users/1", nil},
+		{"user", nil, "", ErrMissingVariable("id")},
+	}
+
+	for i, test := range tests {
+		result, err := m.URL(test.name, test.vars...)
+		if result != test.result || err != test.err {
+			t.Errorf("%v: m.URL(%q) = %q, %v WANT %q, %v", i, test.name, result, err, test.result, test.err)
+		}
+	}
+}
+
+func TestRoute_URL_doesNotEscapeStaticPartsWithUseEscapedPath(t *testing.T) {
+	m := New()
+	m.UseEscapedPath = true
+	m.SubRoute("/a%20b/:id").Name("escaped")
+
+	path, err := m.URL("escaped", &Variable{Name: "id", Value: "c/d"})
+	if want := "/a%20b/c%2Fd"; path != want || err != nil {
+		t.Errorf("m.URL() = %q, %v WANT %q, <nil>", path, err, want)
+	}
+
+	m.UseEscapedPath = false
+	path, err = m.URL("escaped", &Variable{Name: "id", Value: "c"})
+	if want := "/a%2520b/c"; path != want || err != nil {
+		t.Errorf("m.URL() = %q, %v WANT %q, <nil>", path, err, want)
+	}
+}
@@ -3,6 +3,17 @@ package httpmux
 type Variable struct {
 	Name  VarName
 	Value string
+
+	//RawValue is the escaped form of Value. It is the value found in the
+	//request path when the Mux has UseEscapedPath set, in which case Value is
+	//RawValue unescaped, and is Value escaped otherwise. Host variables are
+	//never escaped.
+	RawValue string
+
+	//Constraint is the constraint registered with the variable, e.g. "int" for
+	//the pattern ":id{int}". It is empty if the variable is unconstrained.
+	Constraint string
 }
 
+//VarName is the name of a Variable.
 type VarName string
@@ -0,0 +1,112 @@
+package httpmux
+
+import (
+	"strings"
+
+	muxpath "github.com/gogolfing/httpmux/path"
+)
+
+//walkNodes calls fn with n and pattern, and then with each of n's descendants
+//and their full patterns, in order. pattern must be the full pattern of n.
+//Walking stops and false is returned as soon as fn returns false.
+func walkNodes(n node, pattern string, fn func(n node, pattern string) bool) bool {
+	if !fn(n, pattern) {
+		return false
+	}
+	return forEachChild(n, pattern, func(child node, childPattern string) bool {
+		return walkNodes(child, childPattern, fn)
+	})
+}
+
+//forEachChild calls fn with each of n's children and their full patterns, in
+//order. pattern must be the full pattern of n.
+//It stops and returns false as soon as fn returns false.
+func forEachChild(n node, pattern string, fn func(child node, childPattern string) bool) bool {
+	switch n := n.(type) {
+	case *staticNode:
+		for _, child := range n.staticChildren {
+			if !fn(child, pattern+escapeStaticPattern(child.value)) {
+				return false
+			}
+		}
+		if n.segmentVarChild != nil {
+			child := n.segmentVarChild
+			if !fn(child, pattern+variablePattern(muxpath.SegmentVarRune, child.name, child.constraint)) {
+				return false
+			}
+		}
+		if n.endVarChild != nil {
+			child := n.endVarChild
+			if !fn(child, pattern+variablePattern(muxpath.EndVarRune, child.name, child.constraint)) {
+				return false
+			}
+		}
+
+	case *segmentVarNode:
+		if n.staticChild != nil {
+			return fn(n.staticChild, pattern+escapeStaticPattern(n.staticChild.value))
+		}
+	}
+	return true
+}
+
+//patternBeneath returns whether pattern is at or beneath base when only whole
+//path segments are compared, e.g. "/admin/users" is beneath "/admin", but
+//"/administrators" is not.
+func patternBeneath(pattern, base string) bool {
+	if !strings.HasPrefix(pattern, base) {
+		return false
+	}
+	return len(pattern) == len(base) || strings.HasSuffix(base, muxpath.Slash) || pattern[len(base)] == muxpath.SlashRune
+}
+
+//findPattern returns the node in the tree rooted at root whose full pattern is
+//pattern, or nil if there is none.
+func findPattern(root node, pattern string) node {
+	var result node
+	walkNodes(root, "", func(n node, nodePattern string) bool {
+		if nodePattern == pattern {
+			result = n
+		}
+		return result == nil
+	})
+	return result
+}
+
+//firstRegisteredPattern returns the pattern of the first registered node found
+//in target's subtree, where target is a descendant of n.
+//If no such node is registered, then the pattern of target is returned.
+func firstRegisteredPattern(n node, pattern string, target node) string {
+	result, found := "", false
+	walkNodes(n, pattern, func(current node, currentPattern string) bool {
+		if current == target {
+			result, found = currentPattern, true
+		}
+		return !found
+	})
+	if !found {
+		return ""
+	}
+
+	walkNodes(target, result, func(current node, currentPattern string) bool {
+		if current.isRegistered() {
+			result = currentPattern
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+func escapeStaticPattern(static string) string {
+	static = strings.Replace(static, string(muxpath.SegmentVarRune), string([]rune{muxpath.SegmentVarRune, muxpath.SegmentVarRune}), -1)
+	return strings.Replace(static, string(muxpath.EndVarRune), string([]rune{muxpath.EndVarRune, muxpath.EndVarRune}), -1)
+}
+
+func variablePattern(varRune rune, name VarName, c *constraint) string {
+	result := string(varRune) + string(name)
+	if c != nil {
+		result += string(muxpath.ConstraintStartRune) + c.String() + string(muxpath.ConstraintEndRune)
+	}
+	return result
+}